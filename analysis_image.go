@@ -0,0 +1,104 @@
+package decouplet
+
+import (
+	"image"
+)
+
+// analysisBlockSize is the width and height of the regions
+// an image key is divided into when looking for uniform regions.
+const analysisBlockSize = 32
+
+// analysisUniformRange is the largest range of levels, in every channel,
+// of a region too uniform to be useful.
+const analysisUniformRange = 4
+
+// ImageAnalysis describes how useful an image is as a key.
+type ImageAnalysis struct {
+	// Variance is the number of unique colors as a percentage of 46368,
+	// which may be above 100 for photographs.
+	Variance int `json:"variance"`
+	// Channels holds the characters of the dictionary, in the order of Histograms.
+	Channels string `json:"channels"`
+	// Histograms holds, for each channel, the number of pixels at each level.
+	Histograms [][256]int `json:"histograms"`
+	// Reachable is the number of the 256 bytes which were encoded
+	// within the attempts made when encoding.
+	Reachable int `json:"reachable"`
+	// Unreachable lists the byte values which were not encoded
+	// within the attempts made when encoding.
+	Unreachable []int `json:"unreachable"`
+	// Uniform lists the regions whose levels barely change,
+	// which add little to a key.
+	Uniform []image.Rectangle `json:"uniform"`
+}
+
+// AnalyzeImageKey takes an image and analyzes its usefulness at encoding.
+func AnalyzeImageKey(key image.Image) (ImageAnalysis, error) {
+	k := imageKey{key}
+	if valid, err := k.CheckValid(); !valid {
+		return ImageAnalysis{}, err
+	}
+	width := key.Bounds().Max.X
+	height := key.Bounds().Max.Y
+	planes := imagePlanes(key, width, height)
+	analysis := ImageAnalysis{
+		Variance:    k.checkVariance(),
+		Channels:    k.DictionarySet(),
+		Histograms:  make([][256]int, len(planes)),
+		Unreachable: make([]int, 0),
+		Uniform:     make([]image.Rectangle, 0),
+	}
+	for i, plane := range planes {
+		for _, level := range plane {
+			analysis.Histograms[i][level]++
+		}
+	}
+
+	random := options{}.newRandom()
+	for char := 0; char < 256; char++ {
+		found := false
+		for i := 0; i < matchFindRetriesImage && !found; i++ {
+			found = scanPlanes(planes, width, height, byte(char), random.Rand)
+		}
+		if found {
+			analysis.Reachable++
+		} else {
+			analysis.Unreachable = append(analysis.Unreachable, char)
+		}
+	}
+
+	for y := 0; y < height; y += analysisBlockSize {
+		for x := 0; x < width; x += analysisBlockSize {
+			block := image.Rect(x, y, x+analysisBlockSize, y+analysisBlockSize).
+				Intersect(image.Rect(0, 0, width, height))
+			if uniformBlock(planes, width, block) {
+				analysis.Uniform = append(analysis.Uniform, block)
+			}
+		}
+	}
+	return analysis, nil
+}
+
+// uniformBlock reports whether the levels of every channel
+// within a block are no more than analysisUniformRange apart.
+func uniformBlock(planes [][]uint8, width int, block image.Rectangle) bool {
+	for _, plane := range planes {
+		low := plane[getPixelNumber(block.Min.X, block.Min.Y, width)]
+		high := low
+		for y := block.Min.Y; y < block.Max.Y; y++ {
+			for x := block.Min.X; x < block.Max.X; x++ {
+				level := plane[getPixelNumber(x, y, width)]
+				if level < low {
+					low = level
+				}
+				if level > high {
+					high = level
+				}
+			}
+		}
+		if high-low > analysisUniformRange {
+			return false
+		}
+	}
+	return true
+}
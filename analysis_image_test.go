@@ -0,0 +1,60 @@
+package decouplet
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestAnalyzeImageKey(t *testing.T) {
+	img, err := LoadImage("images/test.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis, err := AnalyzeImageKey(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.Reachable != 256 || len(analysis.Unreachable) != 0 {
+		t.Error("expected every byte reachable, missing:", analysis.Unreachable)
+	}
+	pixels := img.Bounds().Dx() * img.Bounds().Dy()
+	for i, histogram := range analysis.Histograms {
+		total := 0
+		for _, count := range histogram {
+			total += count
+		}
+		if total != pixels {
+			t.Error("histogram", string(analysis.Channels[i]), "counts", total, "pixels")
+		}
+	}
+	if len(analysis.Uniform) != 0 {
+		t.Error("unexpected uniform regions:", analysis.Uniform)
+	}
+}
+
+func TestAnalyzeImageKeyUniform(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 320, 320))
+	draw.Draw(img, img.Bounds(), vectorImageKey(), image.Point{}, draw.Src)
+	flat := image.Rect(0, 0, 64, 64)
+	draw.Draw(img, flat, image.NewUniform(color.NRGBA{R: 40, G: 80, B: 120, A: 255}),
+		image.Point{}, draw.Src)
+	analysis, err := AnalyzeImageKey(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analysis.Uniform) != 4 {
+		t.Error("expected four uniform regions, found:", analysis.Uniform)
+	}
+	for _, region := range analysis.Uniform {
+		if !region.In(flat) {
+			t.Error("region is not uniform:", region)
+		}
+	}
+
+	_, err = AnalyzeImageKey(image.NewRGBA(image.Rect(0, 0, 10, 10)))
+	if err != errorImageKeyTooSmall {
+		t.Error("expected key too small, got:", err)
+	}
+}
@@ -0,0 +1,287 @@
+package decouplet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+const armorBegin = "-----BEGIN DECOUPLET MESSAGE-----"
+const armorEnd = "-----END DECOUPLET MESSAGE-----"
+const armorLineLength = 64
+const armorMaxHeader = 512
+
+const crc24Init = 0xB704CE
+const crc24Poly = 0x1864CFB
+
+var errorArmorMissing = errors.New("armor begin line not found")
+var errorArmorChecksum = errors.New("armor checksum does not match")
+var errorArmorBody = errors.New("armor body is malformed")
+
+// Armor wraps an encoded message in BEGIN and END lines,
+// with headers naming its encoder, a body of wrapped base64 lines
+// and a checksum, so it can be pasted into text.
+func Armor(message []byte) ([]byte, error) {
+	output := &bytes.Buffer{}
+	writer := NewArmorWriter(output)
+	_, err := writer.Write(message)
+	if err != nil {
+		return nil, err
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	return output.Bytes(), nil
+}
+
+// Dearmor returns the encoded message held in armored text.
+func Dearmor(armored []byte) ([]byte, error) {
+	return ioutil.ReadAll(NewArmorReader(bytes.NewReader(armored)))
+}
+
+func crc24(crc uint32, data []byte) uint32 {
+	for _, b := range data {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= crc24Poly
+			}
+		}
+	}
+	return crc & 0xFFFFFF
+}
+
+func armorChecksum(crc uint32) string {
+	return "=" + base64.StdEncoding.EncodeToString(
+		[]byte{byte(crc >> 16), byte(crc >> 8), byte(crc)})
+}
+
+type armorWriter struct {
+	writer  io.Writer
+	lines   *lineWriter
+	body    io.WriteCloser
+	pending []byte
+	started bool
+	crc     uint32
+}
+
+// NewArmorWriter returns a writer which armors an encoded message written to it.
+// The checksum and END line are written when the writer is closed.
+func NewArmorWriter(w io.Writer) io.WriteCloser {
+	lines := &lineWriter{writer: w}
+	return &armorWriter{
+		writer: w,
+		lines:  lines,
+		body:   base64.NewEncoder(base64.StdEncoding, lines),
+		crc:    crc24Init,
+	}
+}
+
+func (a *armorWriter) Write(p []byte) (int, error) {
+	if !a.started {
+		a.pending = append(a.pending, p...)
+		if len(a.pending) == 0 {
+			return 0, nil
+		}
+		if a.pending[0] == headerStart[0] &&
+			bytes.IndexByte(a.pending, headerEnd) < 0 &&
+			len(a.pending) < armorMaxHeader {
+			return len(p), nil
+		}
+		err := a.start()
+		if err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	a.crc = crc24(a.crc, p)
+	return a.body.Write(p)
+}
+
+// start writes the BEGIN line and headers, taking the encoder name and
+// version from the message header if one was written.
+func (a *armorWriter) start() error {
+	a.started = true
+	headers := armorBegin + "\n"
+	if len(a.pending) > 0 && a.pending[0] == headerStart[0] {
+		end := bytes.IndexByte(a.pending, headerEnd)
+		if end > 0 {
+			meta := string(a.pending[len(headerStart):end])
+			meta = strings.Split(meta, headerParamSeparator)[0]
+			if i := strings.Index(meta, "-"); i > 0 {
+				headers += "Encoder: " + meta[:i] + "\n" +
+					"Version: " + meta[i+1:] + "\n"
+			}
+		}
+	}
+	_, err := io.WriteString(a.writer, headers+"\n")
+	if err != nil {
+		return err
+	}
+	pending := a.pending
+	a.pending = nil
+	a.crc = crc24(a.crc, pending)
+	_, err = a.body.Write(pending)
+	return err
+}
+
+func (a *armorWriter) Close() error {
+	if !a.started {
+		err := a.start()
+		if err != nil {
+			return err
+		}
+	}
+	err := a.body.Close()
+	if err != nil {
+		return err
+	}
+	if a.lines.column > 0 {
+		_, err = io.WriteString(a.writer, "\n")
+		if err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(a.writer,
+		armorChecksum(a.crc)+"\n"+armorEnd+"\n")
+	return err
+}
+
+// lineWriter breaks text into lines of armorLineLength.
+type lineWriter struct {
+	writer io.Writer
+	column int
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := armorLineLength - l.column
+		if n > len(p) {
+			n = len(p)
+		}
+		_, err := l.writer.Write(p[:n])
+		if err != nil {
+			return written, err
+		}
+		written += n
+		l.column += n
+		p = p[n:]
+		if l.column == armorLineLength {
+			_, err = io.WriteString(l.writer, "\n")
+			if err != nil {
+				return written, err
+			}
+			l.column = 0
+		}
+	}
+	return written, nil
+}
+
+const (
+	armorStateBegin = iota
+	armorStateHeaders
+	armorStateBody
+	armorStateDone
+)
+
+type armorReader struct {
+	reader   *bufio.Reader
+	state    int
+	encoded  []byte
+	decoded  []byte
+	checksum string
+	crc      uint32
+	err      error
+}
+
+// NewArmorReader returns a reader of the encoded message held in armored text.
+// Whitespace and line endings in the armored text are ignored.
+func NewArmorReader(r io.Reader) io.Reader {
+	return &armorReader{
+		reader: bufio.NewReader(r),
+		crc:    crc24Init,
+	}
+}
+
+func (a *armorReader) Read(p []byte) (int, error) {
+	for len(a.decoded) == 0 && a.err == nil {
+		a.err = a.readLine()
+	}
+	if len(a.decoded) > 0 {
+		n := copy(p, a.decoded)
+		a.decoded = a.decoded[n:]
+		return n, nil
+	}
+	return 0, a.err
+}
+
+func (a *armorReader) readLine() error {
+	line, err := a.reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if err == io.EOF && line == "" {
+		if a.state == armorStateBegin {
+			return errorArmorMissing
+		}
+		return io.ErrUnexpectedEOF
+	}
+	line = strings.TrimSpace(line)
+
+	switch a.state {
+	case armorStateBegin:
+		if line == armorBegin {
+			a.state = armorStateHeaders
+		}
+		return nil
+	case armorStateHeaders:
+		if line == "" {
+			a.state = armorStateBody
+			return nil
+		}
+		if strings.Contains(line, ":") {
+			return nil
+		}
+		a.state = armorStateBody
+	}
+
+	switch {
+	case line == armorEnd:
+		a.state = armorStateDone
+		return a.finish()
+	case len(line) == 5 && line[0] == '=':
+		a.checksum = line
+		return nil
+	}
+	a.encoded = append(a.encoded, strings.Join(strings.Fields(line), "")...)
+	return a.decode(len(a.encoded) - len(a.encoded)%4)
+}
+
+func (a *armorReader) decode(n int) error {
+	decoded := make([]byte, base64.StdEncoding.DecodedLen(n))
+	written, err := base64.StdEncoding.Decode(decoded, a.encoded[:n])
+	if err != nil {
+		return errorArmorBody
+	}
+	a.encoded = a.encoded[n:]
+	a.decoded = decoded[:written]
+	a.crc = crc24(a.crc, a.decoded)
+	return nil
+}
+
+func (a *armorReader) finish() error {
+	if len(a.encoded) > 0 {
+		return errorArmorBody
+	}
+	if a.checksum != armorChecksum(a.crc) {
+		return errorArmorChecksum
+	}
+	return io.EOF
+}
@@ -0,0 +1,127 @@
+package decouplet
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestArmorMessage(t *testing.T) {
+	key := make([]byte, 256)
+	_, err := rand.Read(key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	originalMessage :=
+		"!!**_-+Test THIS bigger message with More Symbols" +
+			"@$_()#$%^#@!~#2364###$%! *(#$%)^@#%$@"
+	newMessage, err := EncodeBytes([]byte(originalMessage), key, WithFormat(FormatBinary))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	armored, err := Armor(newMessage)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	t.Log(string(armored))
+	lines := strings.Split(string(armored), "\n")
+	if lines[0] != armorBegin || lines[1] != "Encoder: byteec" || lines[2] != "Version: 0.2" {
+		t.Error("unexpected armor headers:", lines[:3])
+	}
+	for _, line := range lines {
+		if len(line) > armorLineLength {
+			t.Error("line longer than armor line length:", line)
+		}
+	}
+
+	mangled := "Pasted from a ticket:\r\n\r\n" +
+		strings.Replace(string(armored), "\n", "\r\n   ", -1)
+	dearmored, err := Dearmor([]byte(mangled))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !bytes.Equal(newMessage, dearmored) {
+		t.Error("dearmored message does not match")
+	}
+	message, err := DecodeBytes(dearmored, key)
+	if err != nil {
+		t.Error(err)
+	}
+	if originalMessage != string(message) {
+		t.Fail()
+	}
+}
+
+func TestArmorStream(t *testing.T) {
+	key := make([]byte, 256)
+	_, err := rand.Read(key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	msg := []byte("Test this message and see it stream through armor")
+	reader, err := EncodeBytesStream(bytes.NewReader(msg), key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	armored := &bytes.Buffer{}
+	writer := NewArmorWriter(armored)
+	_, err = io.Copy(writer, reader)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	err = writer.Close()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if strings.Contains(armored.String(), "Encoder:") {
+		t.Error("stream without a header should not have encoder headers")
+	}
+	newReader, err := DecodeBytesStream(NewArmorReader(armored), key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	b, err := ioutil.ReadAll(newReader)
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(msg, b) {
+		t.Log("bytes are not equal")
+		t.Fail()
+	}
+}
+
+func TestArmorChecksum(t *testing.T) {
+	armored, err := Armor([]byte("[dcplt-byteec-0.2]a9c0e8j4j8d4j8c9"))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	lines := strings.Split(string(armored), "\n")
+	body := []byte(lines[4])
+	body[0] ^= 1
+	lines[4] = string(body)
+	_, err = Dearmor([]byte(strings.Join(lines, "\n")))
+	if err != errorArmorChecksum {
+		t.Error("expected checksum error, got:", err)
+	}
+	_, err = Dearmor([]byte("no armor here"))
+	if err != errorArmorMissing {
+		t.Error("expected missing armor error, got:", err)
+	}
+	_, err = Dearmor(armored[:len(armored)-len(armorEnd)-1])
+	if err != io.ErrUnexpectedEOF {
+		t.Error("expected unexpected EOF, got:", err)
+	}
+}
@@ -0,0 +1,145 @@
+package decouplet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+const wavFormatPCM = 1
+const wavFormatExtensible = 0xFFFE
+
+var errorWAVHeader = errors.New("audio is not a RIFF WAVE file")
+var errorWAVFormat = errors.New("audio is not uncompressed 8, 16 or 24-bit PCM")
+var errorWAVData = errors.New("audio is missing fmt or data chunk")
+
+// Audio holds uncompressed PCM samples.
+// Samples are interleaved by channel and stored little-endian,
+// as they appear in a WAV file.
+type Audio struct {
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+	Data          []byte
+}
+
+// LoadAudio loads an uncompressed WAV file from disk.
+func LoadAudio(filename string) (*Audio, error) {
+	audioFile, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer audioFile.Close()
+
+	return ReadAudio(audioFile)
+}
+
+// ReadAudio reads an uncompressed WAV file
+// with 8, 16 or 24-bit samples and any number of channels.
+func ReadAudio(r io.Reader) (*Audio, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 12 ||
+		!bytes.Equal(data[0:4], []byte("RIFF")) ||
+		!bytes.Equal(data[8:12], []byte("WAVE")) {
+		return nil, errorWAVHeader
+	}
+
+	audio := &Audio{}
+	foundFormat := false
+	foundData := false
+	for chunk := data[12:]; len(chunk) >= 8; {
+		id := string(chunk[0:4])
+		size := int(binary.LittleEndian.Uint32(chunk[4:8]))
+		chunk = chunk[8:]
+		if size > len(chunk) {
+			size = len(chunk)
+		}
+		body := chunk[:size]
+
+		switch id {
+		case "fmt ":
+			if err := audio.readFormat(body); err != nil {
+				return nil, err
+			}
+			foundFormat = true
+		case "data":
+			audio.Data = body
+			foundData = true
+		}
+
+		if size%2 == 1 && size < len(chunk) {
+			size++
+		}
+		chunk = chunk[size:]
+	}
+	if !foundFormat || !foundData {
+		return nil, errorWAVData
+	}
+	frameSize := audio.frameSize()
+	audio.Data = audio.Data[:len(audio.Data)-len(audio.Data)%frameSize]
+	return audio, nil
+}
+
+func (a *Audio) readFormat(body []byte) error {
+	if len(body) < 16 {
+		return errorWAVFormat
+	}
+	format := binary.LittleEndian.Uint16(body[0:2])
+	if format == wavFormatExtensible {
+		if len(body) < 26 {
+			return errorWAVFormat
+		}
+		format = binary.LittleEndian.Uint16(body[24:26])
+	}
+	if format != wavFormatPCM {
+		return errorWAVFormat
+	}
+	a.Channels = int(binary.LittleEndian.Uint16(body[2:4]))
+	a.SampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+	a.BitsPerSample = int(binary.LittleEndian.Uint16(body[14:16]))
+	switch a.BitsPerSample {
+	case 8, 16, 24:
+	default:
+		return errorWAVFormat
+	}
+	if a.Channels < 1 {
+		return errorWAVFormat
+	}
+	return nil
+}
+
+// Frames returns the number of sample frames, one sample for each channel.
+func (a *Audio) Frames() int {
+	return len(a.Data) / a.frameSize()
+}
+
+func (a *Audio) frameSize() int {
+	return a.Channels * (a.BitsPerSample / 8)
+}
+
+func (a *Audio) frame(frameNumber int) []byte {
+	size := a.frameSize()
+	return a.Data[frameNumber*size : (frameNumber+1)*size]
+}
+
+// levels returns the level of each channel in a frame, the high byte of its
+// sample. Samples wider than 8 bits are signed, so their sign bit is flipped
+// to match 8-bit samples, which are unsigned with silence at 128.
+func (a *Audio) levels(frameNumber int) []byte {
+	frame := a.frame(frameNumber)
+	sampleSize := a.BitsPerSample / 8
+	levels := make([]byte, a.Channels)
+	for i := range levels {
+		levels[i] = frame[(i+1)*sampleSize-1]
+		if sampleSize > 1 {
+			levels[i] ^= 0x80
+		}
+	}
+	return levels
+}
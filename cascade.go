@@ -0,0 +1,137 @@
+package decouplet
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+)
+
+var errorCascadeKeys = errors.New("keys do not match cascade layers")
+
+// cascadeInfo identifies a message encoded against several keys in turn.
+var cascadeInfo = EncoderInfo{
+	Name:    "cascade",
+	Version: "0.1",
+}
+
+// EncodeCascade encodes a slice of bytes against each key in turn,
+// so that every key is needed to decode it. The output of each layer
+// is encoded against the next key, and the layers are listed in a header.
+// Options are applied to every layer. As each layer expands its input,
+// cascaded messages grow quickly with the number of keys.
+func EncodeCascade(input []byte, keys []Key, opts ...Option) ([]byte, error) {
+	meta, err := cascadeHeader(keys)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		input, err = Encode(input, key, opts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return append(meta, input...), nil
+}
+
+// EncodeCascadeStream encodes a byte stream against each key in turn.
+func EncodeCascadeStream(input io.Reader, keys []Key, opts ...Option) (*io.PipeReader, error) {
+	meta, err := cascadeHeader(keys)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		input, err = EncodeStream(input, key, opts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	reader, writer := io.Pipe()
+	go func() {
+		_, err := io.Copy(writer, io.MultiReader(bytes.NewReader(meta), input))
+		writer.CloseWithError(err)
+	}()
+	return reader, nil
+}
+
+// DecodeCascade decodes a message encoded with EncodeCascade.
+// Keys are given in the order they were encoded against.
+func DecodeCascade(input []byte, keys []Key) ([]byte, error) {
+	end := bytes.IndexByte(input, headerEnd)
+	if end < 0 {
+		return nil, ErrEncoderVersion
+	}
+	err := checkCascade(string(input[:end+1]), keys)
+	if err != nil {
+		return nil, err
+	}
+	input = input[end+1:]
+	for i := len(keys) - 1; i >= 0; i-- {
+		input, err = Decode(input, keys[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return input, nil
+}
+
+// DecodeCascadeStream decodes a stream encoded with EncodeCascadeStream.
+// Keys are given in the order they were encoded against.
+func DecodeCascadeStream(input io.Reader, keys []Key) (*io.PipeReader, error) {
+	buffered := bufio.NewReader(input)
+	meta, err := buffered.ReadSlice(headerEnd)
+	if err != nil {
+		return nil, ErrEncoderVersion
+	}
+	err = checkCascade(string(meta), keys)
+	if err != nil {
+		return nil, err
+	}
+	var reader io.Reader = buffered
+	var decoded *io.PipeReader
+	for i := len(keys) - 1; i >= 0; i-- {
+		decoded, err = DecodeStream(reader, keys[i])
+		if err != nil {
+			return nil, err
+		}
+		reader = decoded
+	}
+	return decoded, nil
+}
+
+// cascadeHeader returns the header listing the encoder of each layer.
+func cascadeHeader(keys []Key) ([]byte, error) {
+	if len(keys) == 0 {
+		return nil, errorCascadeKeys
+	}
+	layers := make([]string, len(keys))
+	for i, key := range keys {
+		info := key.Version()
+		layers[i] = info.Name + "-" + info.Version
+	}
+	meta, err := cascadeInfo.getEncoderString(layers...)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(meta), nil
+}
+
+// checkCascade checks a cascade header lists the encoders of the keys, in order.
+func checkCascade(meta string, keys []Key) error {
+	expected, err := cascadeHeader(keys)
+	if err != nil {
+		return err
+	}
+	if meta == string(expected) {
+		return nil
+	}
+	start := headerStart + cascadeInfo.Name + "-" + cascadeInfo.Version
+	if !strings.HasPrefix(meta, start) {
+		return ErrEncoderVersion
+	}
+	if strings.Count(meta, headerParamSeparator) != len(keys) {
+		return errorCascadeKeys
+	}
+	return ErrEncoderVersion
+}
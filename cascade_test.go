@@ -0,0 +1,85 @@
+package decouplet
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func cascadeKeys(t *testing.T) []Key {
+	img, err := LoadImage("images/test.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []Key{NewBytesKey(vectorBytesKey()), NewImageKey(img)}
+}
+
+func TestCascadeMessage(t *testing.T) {
+	keys := cascadeKeys(t)
+	msg := []byte("Test cascade")
+	encoded, err := EncodeCascade(msg, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(encoded, []byte("[dcplt-cascade-0.1;byteec-0.2;imgec-0.2]")) {
+		t.Error("unexpected cascade header:", string(encoded[:48]))
+	}
+	decoded, err := DecodeCascade(encoded, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(msg, decoded) {
+		t.Error("bytes are not equal")
+	}
+
+	_, err = DecodeCascade(encoded, []Key{keys[1], keys[0]})
+	if err != ErrEncoderVersion {
+		t.Error("expected encoder version error, got:", err)
+	}
+	_, err = DecodeCascade(encoded, keys[:1])
+	if err != errorCascadeKeys {
+		t.Error("expected cascade keys error, got:", err)
+	}
+	_, err = EncodeCascade(msg, nil)
+	if err != errorCascadeKeys {
+		t.Error("expected cascade keys error, got:", err)
+	}
+}
+
+func TestCascadeWrongKey(t *testing.T) {
+	keys := cascadeKeys(t)
+	encoded, err := EncodeCascade([]byte("Test"), keys, WithFingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := make([]byte, 256)
+	for i := range other {
+		other[i] = byte(i * 3)
+	}
+	_, err = DecodeCascade(encoded, []Key{NewBytesKey(other), keys[1]})
+	if err != ErrWrongKey {
+		t.Error("expected wrong key error, got:", err)
+	}
+}
+
+func TestCascadeStream(t *testing.T) {
+	keys := cascadeKeys(t)
+	msg := []byte("Test this cascade as a stream")
+	for _, opts := range [][]Option{nil, {WithFormat(FormatBinary), WithIntegrity()}} {
+		reader, err := EncodeCascadeStream(bytes.NewReader(msg), keys, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeCascadeStream(reader, keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(decoded)
+		if err != nil {
+			t.Error(err)
+		}
+		if !bytes.Equal(msg, b) {
+			t.Error("bytes are not equal")
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/marcsj/decouplet"
+)
+
+const keyUsage = `usage: decouplet key <command> [flags]
+
+commands:
+  gen      generate a random key
+  analyze  report how well suited a key is to encoding
+
+run decouplet key <command> -h for the flags of a command.
+`
+
+const (
+	keyTypeBytes = "bytes"
+	keyTypeImage = "image"
+)
+
+// keyAnalysis is the output of key analyze in JSON.
+type keyAnalysis struct {
+	Report decouplet.KeyReport      `json:"report"`
+	Image  *decouplet.ImageAnalysis `json:"image,omitempty"`
+}
+
+func runKey(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, keyUsage)
+		return exitUsage
+	}
+	switch args[0] {
+	case "gen":
+		return runKeyGen(args[1:], stdout, stderr)
+	case "analyze":
+		return runKeyAnalyze(args[1:], stdout, stderr)
+	}
+	fmt.Fprintf(stderr, "decouplet: unknown key command %q\n%s", args[0], keyUsage)
+	return exitUsage
+}
+
+// runKeyGen writes a key of random bytes, or a PNG image of random pixels.
+func runKeyGen(args []string, stdout io.Writer, stderr io.Writer) int {
+	set := flagSet("key gen", stderr)
+	keyType := set.String("type", keyTypeBytes, "type of key, bytes or image")
+	size := set.Int("size", 1024, "size of a bytes key")
+	width := set.Int("width", 512, "width of an image key")
+	height := set.Int("height", 512, "height of an image key")
+	out := set.String("out", "", "file to write the key to, instead of stdout")
+	if code := parseArgs(set, args, stderr); code != exitOK {
+		return code
+	}
+	if *size <= 0 || *width <= 0 || *height <= 0 {
+		fmt.Fprintln(stderr, "decouplet: -size, -width and -height must be above zero")
+		return exitUsage
+	}
+
+	var write func(io.Writer) error
+	switch *keyType {
+	case keyTypeBytes:
+		key := make([]byte, *size)
+		_, err := io.ReadFull(rand.Reader, key)
+		if err != nil {
+			return fail(stderr, err)
+		}
+		if valid, err := decouplet.NewBytesKey(key).CheckValid(); !valid {
+			return fail(stderr, err)
+		}
+		write = func(w io.Writer) error {
+			_, err := w.Write(key)
+			return err
+		}
+	case keyTypeImage:
+		img, err := randomImage(*width, *height)
+		if err != nil {
+			return fail(stderr, err)
+		}
+		if valid, err := decouplet.NewImageKey(img).CheckValid(); !valid {
+			return fail(stderr, err)
+		}
+		write = func(w io.Writer) error {
+			return png.Encode(w, img)
+		}
+	default:
+		fmt.Fprintf(stderr, "decouplet: unknown key type %q\n", *keyType)
+		return exitUsage
+	}
+
+	if *out == "" {
+		return fail(stderr, write(stdout))
+	}
+	f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	err = write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return fail(stderr, err)
+}
+
+// randomImage returns an opaque image of random pixels,
+// which has as much variance as an image of its size can.
+func randomImage(width int, height int) (image.Image, error) {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	levels := make([]byte, width*3)
+	for y := 0; y < height; y++ {
+		_, err := io.ReadFull(rand.Reader, levels)
+		if err != nil {
+			return nil, err
+		}
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{
+				R: levels[x*3], G: levels[x*3+1], B: levels[x*3+2], A: 255})
+		}
+	}
+	return img, nil
+}
+
+// runKeyAnalyze reports on a key, exiting with exitKey if it does not pass.
+func runKeyAnalyze(args []string, stdout io.Writer, stderr io.Writer) int {
+	set, k := newFlagSet("key analyze", stderr)
+	asJSON := set.Bool("json", false, "write the analysis as JSON")
+	if code := parseArgs(set, args, stderr); code != exitOK {
+		return code
+	}
+
+	analysis := keyAnalysis{}
+	switch {
+	case k.bytes != "" && k.image == "":
+		key, err := ioutil.ReadFile(k.bytes)
+		if err != nil {
+			return fail(stderr, err)
+		}
+		analysis.Report = decouplet.ReportBytesKey(key)
+	case k.image != "" && k.bytes == "":
+		img, err := decouplet.LoadImage(k.image)
+		if err != nil {
+			return fail(stderr, err)
+		}
+		analysis.Report = decouplet.ReportImageKey(img)
+		if analysis.Report.Passed {
+			imageAnalysis, err := decouplet.AnalyzeImageKey(img)
+			if err != nil {
+				return fail(stderr, err)
+			}
+			analysis.Image = &imageAnalysis
+		}
+	default:
+		fmt.Fprintln(stderr, "decouplet:", errorKeyFlags)
+		set.Usage()
+		return exitUsage
+	}
+
+	var err error
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(analysis)
+	} else {
+		err = writeAnalysis(stdout, analysis)
+	}
+	if err != nil {
+		return fail(stderr, err)
+	}
+	if !analysis.Report.Passed {
+		return exitKey
+	}
+	return exitOK
+}
+
+// writeAnalysis writes an analysis for people to read.
+func writeAnalysis(w io.Writer, analysis keyAnalysis) error {
+	r := analysis.Report
+	lines := []string{
+		fmt.Sprintf("encoder:      %s-%s", r.Encoder.Name, r.Encoder.Version),
+		fmt.Sprintf("variance:     %d%%", r.Variance),
+		fmt.Sprintf("reachable:    %d of 256 bytes", 256-len(r.Unreachable)),
+	}
+	if len(r.Unreachable) > 0 {
+		lines = append(lines, fmt.Sprintf("unreachable:  %v", r.Unreachable))
+	}
+	lines = append(lines,
+		fmt.Sprintf("retry rate:   %.3f", r.RetryRate),
+		fmt.Sprintf("expansion:    %.2f bytes per byte, %.2f in binary", r.Expansion, r.BinaryExpansion))
+	if analysis.Image != nil {
+		lines = append(lines,
+			fmt.Sprintf("channels:     %s", analysis.Image.Channels),
+			fmt.Sprintf("uniform:      %d regions", len(analysis.Image.Uniform)))
+	}
+	if r.Passed {
+		lines = append(lines, "passed:       yes")
+	} else {
+		lines = append(lines, "passed:       no, "+strings.Join(r.Reasons, "; "))
+	}
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
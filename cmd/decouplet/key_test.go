@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/marcsj/decouplet"
+)
+
+func TestKeyGen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "decouplet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, stderr, code := runCommand("", "key", "gen", "--type", "bytes", "--size", "512")
+	if code != exitOK || len(key) != 512 {
+		t.Fatal("unexpected bytes key:", len(key), code, stderr)
+	}
+	if report := decouplet.ReportBytesKey([]byte(key)); !report.Passed {
+		t.Error("generated bytes key did not pass:", report.Reasons)
+	}
+
+	imageFile := filepath.Join(dir, "key.png")
+	_, stderr, code = runCommand("", "key", "gen", "-type", "image",
+		"-width", "320", "-height", "330", "-out", imageFile)
+	if code != exitOK {
+		t.Fatal(code, stderr)
+	}
+	img, err := decouplet.LoadImage(imageFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 320 || img.Bounds().Dy() != 330 {
+		t.Error("unexpected image size:", img.Bounds())
+	}
+	if report := decouplet.ReportImageKey(img); !report.Passed {
+		t.Error("generated image key did not pass:", report.Reasons)
+	}
+
+	encoded, _, code := runCommand(testMessage, "encode", "-image", imageFile)
+	if code != exitOK {
+		t.Fatal("could not encode with generated key")
+	}
+	decoded, _, code := runCommand(encoded, "decode", "-image", imageFile)
+	if code != exitOK || decoded != testMessage {
+		t.Error("could not decode with generated key:", decoded)
+	}
+
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{[]string{"key"}, exitUsage},
+		{[]string{"key", "rotate"}, exitUsage},
+		{[]string{"key", "gen", "-type", "audio"}, exitUsage},
+		{[]string{"key", "gen", "-size", "0"}, exitUsage},
+		{[]string{"key", "gen", "-size", "16"}, exitKey},
+		{[]string{"key", "gen", "-type", "image", "-width", "100"}, exitKey},
+		{[]string{"key", "gen", "-out", filepath.Join(dir, "missing", "key.bin")}, exitError},
+	}
+	for _, test := range tests {
+		_, stderr, code := runCommand("", test.args...)
+		if code != test.code {
+			t.Error("unexpected exit code:", test.args, code, stderr)
+		}
+	}
+}
+
+func TestKeyAnalyze(t *testing.T) {
+	dir, err := ioutil.TempDir("", "decouplet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile, imageFile := writeKeys(t, dir)
+	weakFile := filepath.Join(dir, "weak.bin")
+	err = ioutil.WriteFile(weakFile, bytes.Repeat([]byte{7}, 128), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, stderr, code := runCommand("", "key", "analyze", "-key", keyFile)
+	if code != exitOK {
+		t.Fatal(code, stderr)
+	}
+	if !strings.Contains(output, "encoder:      byteec-0.2") ||
+		!strings.Contains(output, "passed:       yes") {
+		t.Error("unexpected analysis:", output)
+	}
+
+	output, stderr, code = runCommand("", "key", "analyze", "-image", imageFile, "-json")
+	if code != exitOK {
+		t.Fatal(code, stderr)
+	}
+	analysis := keyAnalysis{}
+	err = json.Unmarshal([]byte(output), &analysis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !analysis.Report.Passed || analysis.Image == nil ||
+		analysis.Image.Channels != "rgbacmyk" || len(analysis.Image.Histograms) != 8 {
+		t.Error("unexpected analysis:", output)
+	}
+
+	output, _, code = runCommand("", "key", "analyze", "-key", weakFile)
+	if code != exitKey || !strings.Contains(output, "passed:       no") {
+		t.Error("expected weak key to fail:", code, output)
+	}
+	_, _, code = runCommand("", "key", "analyze")
+	if code != exitUsage {
+		t.Error("expected usage error, got:", code)
+	}
+}
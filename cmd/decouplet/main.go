@@ -0,0 +1,279 @@
+// Command decouplet encodes and decodes streams from stdin to stdout
+// against a byte key file or an image key.
+//
+// Usage:
+//
+//	decouplet encode         -key file | -image file [-binary] [-fingerprint] [-integrity]
+//	decouplet decode         -key file | -image file
+//	decouplet encode-partial -key file | -image file -take n -skip n [-start marker -end marker]
+//	decouplet decode-partial -key file | -image file
+//	decouplet key gen        [-type bytes -size n | -type image -width n -height n] [-out file]
+//	decouplet key analyze    -key file | -image file [-json]
+//
+// The exit code is 0 on success, 1 for other errors, 2 for usage errors,
+// 3 when a message was encoded by a different encoder or version,
+// 4 when a key is not valid, does not match a message, or does not pass analysis,
+// and 5 when a message cannot be decoded.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/marcsj/decouplet"
+)
+
+const (
+	exitOK = iota
+	exitError
+	exitUsage
+	exitVersion
+	exitKey
+	exitDecode
+)
+
+const usage = `usage: decouplet <command> [flags]
+
+commands:
+  encode          encode stdin to stdout
+  decode          decode stdin to stdout
+  encode-partial  encode parts of stdin to stdout
+  decode-partial  decode a partially encoded stream from stdin to stdout
+  key             generate and analyze keys
+
+run decouplet <command> -h for the flags of a command.
+`
+
+var errorKeyFlags = errors.New("one of -key or -image is required")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs a command, returning the code to exit with.
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+	commands := map[string]func([]string, io.Reader, io.Writer, io.Writer) int{
+		"encode":         runEncode,
+		"decode":         runDecode,
+		"encode-partial": runEncodePartial,
+		"decode-partial": runDecodePartial,
+		"key":            runKey,
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "decouplet: unknown command %q\n%s", args[0], usage)
+		return exitUsage
+	}
+	return command(args[1:], stdin, stdout, stderr)
+}
+
+// keyFlags are the flags naming the key of a command.
+type keyFlags struct {
+	bytes string
+	image string
+}
+
+func flagSet(name string, stderr io.Writer) *flag.FlagSet {
+	set := flag.NewFlagSet(name, flag.ContinueOnError)
+	set.SetOutput(stderr)
+	return set
+}
+
+func newFlagSet(name string, stderr io.Writer) (*flag.FlagSet, *keyFlags) {
+	set := flagSet(name, stderr)
+	k := &keyFlags{}
+	set.StringVar(&k.bytes, "key", "", "file holding a byte key")
+	set.StringVar(&k.image, "image", "", "image file to use as a key")
+	return set, k
+}
+
+func (k *keyFlags) load() (decouplet.Key, error) {
+	switch {
+	case k.bytes != "" && k.image == "":
+		b, err := ioutil.ReadFile(k.bytes)
+		if err != nil {
+			return nil, err
+		}
+		return decouplet.NewBytesKey(b), nil
+	case k.image != "" && k.bytes == "":
+		img, err := decouplet.LoadImage(k.image)
+		if err != nil {
+			return nil, err
+		}
+		return decouplet.NewImageKey(img), nil
+	}
+	return nil, errorKeyFlags
+}
+
+// parseArgs parses the flags of a command, which takes no other arguments,
+// returning the code to exit with.
+func parseArgs(set *flag.FlagSet, args []string, stderr io.Writer) int {
+	if err := set.Parse(args); err != nil {
+		return exitUsage
+	}
+	if set.NArg() > 0 {
+		fmt.Fprintf(stderr, "decouplet: unexpected argument %q\n", set.Arg(0))
+		return exitUsage
+	}
+	return exitOK
+}
+
+// parse parses the flags of a command and loads its key,
+// returning the code to exit with if it fails.
+func parse(set *flag.FlagSet, k *keyFlags, args []string, stderr io.Writer) (decouplet.Key, int) {
+	if code := parseArgs(set, args, stderr); code != exitOK {
+		return nil, code
+	}
+	key, err := k.load()
+	if err == errorKeyFlags {
+		fmt.Fprintln(stderr, "decouplet:", err)
+		set.Usage()
+		return nil, exitUsage
+	}
+	if err != nil {
+		return nil, fail(stderr, err)
+	}
+	if valid, err := key.CheckValid(); !valid {
+		return nil, fail(stderr, err)
+	}
+	return key, exitOK
+}
+
+func runEncode(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	set, k := newFlagSet("encode", stderr)
+	binary := set.Bool("binary", false, "write locations as varints")
+	fingerprint := set.Bool("fingerprint", false, "write a fingerprint of the key")
+	integrity := set.Bool("integrity", false, "write an integrity tag")
+	key, code := parse(set, k, args, stderr)
+	if code != exitOK {
+		return code
+	}
+	// Always write a header, so decoding with the wrong type of key
+	// exits with exitVersion rather than failing to decode.
+	opts := []decouplet.Option{decouplet.WithHeader()}
+	if *binary {
+		opts = append(opts, decouplet.WithFormat(decouplet.FormatBinary))
+	}
+	if *fingerprint {
+		opts = append(opts, decouplet.WithFingerprint())
+	}
+	if *integrity {
+		opts = append(opts, decouplet.WithIntegrity())
+	}
+	output := &outputWriter{writer: stdout}
+	encoder := decouplet.NewEncoder(output, key, opts...)
+	_, err := io.Copy(encoder, stdin)
+	if err == nil {
+		err = encoder.Close()
+	}
+	return finish(stderr, output, err, false)
+}
+
+func runDecode(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	set, k := newFlagSet("decode", stderr)
+	key, code := parse(set, k, args, stderr)
+	if code != exitOK {
+		return code
+	}
+	output := &outputWriter{writer: stdout}
+	_, err := io.Copy(output, decouplet.NewDecoder(stdin, key))
+	return finish(stderr, output, err, true)
+}
+
+func runEncodePartial(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	set, k := newFlagSet("encode-partial", stderr)
+	take := set.Int("take", 0, "number of bytes to encode in turn")
+	skip := set.Int("skip", 0, "number of bytes to leave after each encoded part")
+	start := set.String("start", "", "marker before each encoded part")
+	end := set.String("end", "", "marker after each encoded part")
+	key, code := parse(set, k, args, stderr)
+	if code != exitOK {
+		return code
+	}
+	if *take <= 0 || *skip < 0 || (*start == "") != (*end == "") {
+		fmt.Fprintln(stderr, "decouplet: -take must be above zero, -skip may not be negative,"+
+			" and -start and -end are given together")
+		return exitUsage
+	}
+	opts := make([]decouplet.Option, 0)
+	if *start != "" {
+		opts = append(opts, decouplet.WithPartialMarkers(*start, *end))
+	}
+	reader, err := decouplet.EncodeStreamPartial(stdin, key, *take, *skip, opts...)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	output := &outputWriter{writer: stdout}
+	_, err = io.Copy(output, reader)
+	return finish(stderr, output, err, false)
+}
+
+func runDecodePartial(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	set, k := newFlagSet("decode-partial", stderr)
+	key, code := parse(set, k, args, stderr)
+	if code != exitOK {
+		return code
+	}
+	reader, err := decouplet.DecodeStreamPartial(stdin, key)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	output := &outputWriter{writer: stdout}
+	_, err = io.Copy(output, reader)
+	return finish(stderr, output, err, true)
+}
+
+// outputWriter keeps any error writing to stdout,
+// so that it is not reported as an error decoding.
+type outputWriter struct {
+	writer io.Writer
+	err    error
+}
+
+func (o *outputWriter) Write(p []byte) (int, error) {
+	n, err := o.writer.Write(p)
+	if err != nil {
+		o.err = err
+	}
+	return n, err
+}
+
+// finish reports an error from copying a stream, returning the code to exit with.
+// Errors reading a decoded stream are decode errors, unless they are more specific.
+func finish(stderr io.Writer, output *outputWriter, err error, decoding bool) int {
+	if err != nil && err == output.err {
+		fmt.Fprintln(stderr, "decouplet:", err)
+		return exitError
+	}
+	code := fail(stderr, err)
+	if code == exitError && decoding {
+		return exitDecode
+	}
+	return code
+}
+
+// fail reports an error, returning the code to exit with.
+func fail(stderr io.Writer, err error) int {
+	if err == nil {
+		return exitOK
+	}
+	fmt.Fprintln(stderr, "decouplet:", err)
+	var decodeErr *decouplet.DecodeError
+	switch {
+	case errors.Is(err, decouplet.ErrEncoderVersion):
+		return exitVersion
+	case errors.Is(err, decouplet.ErrInvalidKey), errors.Is(err, decouplet.ErrWrongKey):
+		return exitKey
+	case errors.As(err, &decodeErr), errors.Is(err, decouplet.ErrIntegrity):
+		return exitDecode
+	}
+	return exitError
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testMessage = "This is a message to pass through the command ;[& \\ &];"
+
+// writeKeys writes a byte key and an image key to dir.
+func writeKeys(t *testing.T, dir string) (string, string) {
+	key := make([]byte, 256)
+	for i := range key {
+		key[i] = byte(i*37 + 11)
+	}
+	keyFile := filepath.Join(dir, "key.bin")
+	err := ioutil.WriteFile(keyFile, key, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, 320, 320))
+	for y := 0; y < 320; y++ {
+		for x := 0; x < 320; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(x*7 + y*3), G: uint8(x * y), B: uint8(x ^ y), A: 255})
+		}
+	}
+	imageFile := filepath.Join(dir, "key.png")
+	f, err := os.Create(imageFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	err = png.Encode(f, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyFile, imageFile
+}
+
+func runCommand(input string, args ...string) (string, string, int) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	code := run(args, strings.NewReader(input), stdout, stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+func TestRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "decouplet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile, imageFile := writeKeys(t, dir)
+
+	tests := []struct {
+		encode []string
+		decode []string
+	}{
+		{[]string{"encode", "-key", keyFile}, []string{"decode", "-key", keyFile}},
+		{[]string{"encode", "-key", keyFile, "-binary", "-fingerprint", "-integrity"},
+			[]string{"decode", "-key", keyFile}},
+		{[]string{"encode", "-image", imageFile}, []string{"decode", "-image", imageFile}},
+		{[]string{"encode-partial", "-key", keyFile, "-take", "4", "-skip", "6"},
+			[]string{"decode-partial", "-key", keyFile}},
+		{[]string{"encode-partial", "-image", imageFile, "-take", "3", "-skip", "3",
+			"-start", "<<", "-end", ">>"},
+			[]string{"decode-partial", "-image", imageFile}},
+	}
+	for _, test := range tests {
+		encoded, stderr, code := runCommand(testMessage, test.encode...)
+		if code != exitOK {
+			t.Fatal(test.encode, code, stderr)
+		}
+		decoded, stderr, code := runCommand(encoded, test.decode...)
+		if code != exitOK {
+			t.Fatal(test.decode, code, stderr)
+		}
+		if decoded != testMessage {
+			t.Error("decoded message is not equal:", test.encode, decoded)
+		}
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "decouplet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile, imageFile := writeKeys(t, dir)
+	shortFile := filepath.Join(dir, "short.bin")
+	err = ioutil.WriteFile(shortFile, []byte("short"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherFile := filepath.Join(dir, "other.bin")
+	err = ioutil.WriteFile(otherFile, bytes.Repeat([]byte("other key "), 10), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoded, _, _ := runCommand(testMessage, "encode", "-key", keyFile, "-fingerprint")
+	imageEncoded, _, _ := runCommand(testMessage, "encode", "-image", imageFile, "-binary")
+	plain, _, _ := runCommand(testMessage, "encode", "-key", keyFile)
+	integrity, _, _ := runCommand(testMessage, "encode", "-key", keyFile, "-integrity")
+	partial, _, _ := runCommand(testMessage, "encode-partial", "-key", keyFile, "-take", "4", "-skip", "4")
+	tampered := integrity[:len(integrity)-2] + "0]"
+	if tampered == integrity {
+		tampered = integrity[:len(integrity)-2] + "1]"
+	}
+
+	tests := []struct {
+		input string
+		args  []string
+		code  int
+	}{
+		{"", nil, exitUsage},
+		{"", []string{"unknown"}, exitUsage},
+		{"", []string{"encode"}, exitUsage},
+		{"", []string{"encode", "-key", keyFile, "-image", imageFile}, exitUsage},
+		{"", []string{"encode", "-key", keyFile, "extra"}, exitUsage},
+		{"", []string{"encode-partial", "-key", keyFile}, exitUsage},
+		{"", []string{"encode", "-key", filepath.Join(dir, "missing")}, exitError},
+		{"", []string{"encode", "-key", shortFile}, exitKey},
+		{"", []string{"encode-partial", "-key", keyFile, "-take", "1", "-start", "a", "-end", "b"},
+			exitError},
+		{encoded, []string{"decode", "-key", otherFile}, exitKey},
+		{imageEncoded, []string{"decode", "-key", keyFile}, exitVersion},
+		{plain, []string{"decode", "-image", imageFile}, exitVersion},
+		{tampered, []string{"decode", "-key", keyFile}, exitDecode},
+		{"a1b", []string{"decode", "-key", keyFile}, exitDecode},
+		{partial[:len(partial)-1], []string{"decode-partial", "-key", keyFile}, exitDecode},
+	}
+	for _, test := range tests {
+		_, stderr, code := runCommand(test.input, test.args...)
+		if code != test.code {
+			t.Error("unexpected exit code:", test.args, code, stderr)
+		}
+	}
+}
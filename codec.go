@@ -0,0 +1,49 @@
+package decouplet
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// Codec holds the functions used to encode bytes to, and decode bytes from,
+// locations in a Key.
+//
+// Encode must return each location as a single character from the key's
+// DictionarySet followed by text containing no characters from that set,
+// such as "c1234f98" for a key with two groups.
+type Codec struct {
+	// Groups is the number of locations written for each encoded byte.
+	Groups int
+	// Encode returns the encoded locations for a single byte, choosing them
+	// only with the given source, so that seeded output can be reproduced.
+	Encode func(byte, Key, *rand.Rand) ([]byte, error)
+	// Decode returns the byte described by a group of locations.
+	Decode func(Key, DecodeGroup) (byte, error)
+}
+
+var codecsMu sync.RWMutex
+var codecs = map[string]Codec{}
+
+// RegisterCodec makes a Codec available to keys whose Version has the given name.
+// It panics if the name is registered twice, or the codec is incomplete.
+func RegisterCodec(name string, codec Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	if codec.Encode == nil || codec.Decode == nil || codec.Groups < 1 {
+		panic("decouplet: RegisterCodec called with incomplete codec for " + name)
+	}
+	if _, dup := codecs[name]; dup {
+		panic("decouplet: RegisterCodec called twice for " + name)
+	}
+	codecs[name] = codec
+}
+
+func getCodec(key Key) (Codec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	codec, ok := codecs[key.Version().Name]
+	if !ok {
+		return Codec{}, errorCodecNotFound
+	}
+	return codec, nil
+}
@@ -0,0 +1,153 @@
+package decouplet
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+type indexKey []byte
+
+func (indexKey) Version() EncoderInfo {
+	return EncoderInfo{
+		Name:    "idxtest",
+		Version: "0.1",
+	}
+}
+
+func (k indexKey) CheckValid() (bool, error) {
+	if len(k) == 0 {
+		return false, errors.New("empty index key")
+	}
+	return true, nil
+}
+
+func (indexKey) DictionarySet() string {
+	return "x"
+}
+
+func findIndex(char byte, key Key, random *rand.Rand) ([]byte, error) {
+	k := key.(indexKey)
+	i := bytes.IndexByte(k, char)
+	if i < 0 {
+		return nil, errorMatchNotFound
+	}
+	return []byte("x" + strconv.Itoa(i)), nil
+}
+
+func getIndex(key Key, group DecodeGroup) (byte, error) {
+	k := key.(indexKey)
+	loc, err := strconv.Atoi(group.Place[0])
+	if err != nil {
+		return 0, err
+	}
+	if loc >= len(k) {
+		return 0, errorDecodeGeneric
+	}
+	return k[loc], nil
+}
+
+func init() {
+	RegisterCodec(indexKey(nil).Version().Name, Codec{
+		Groups: 1,
+		Encode: findIndex,
+		Decode: getIndex,
+	})
+}
+
+func newIndexKey() indexKey {
+	key := make(indexKey, 256)
+	for i := range key {
+		key[i] = byte(255 - i)
+	}
+	return key
+}
+
+func TestCodecMessage(t *testing.T) {
+	key := newIndexKey()
+	msg := []byte("Test this message against a registered key")
+	encoded, err := Encode(msg, key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	t.Log(string(encoded))
+	decoded, err := Decode(encoded, key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !bytes.Equal(msg, decoded) {
+		t.Log("bytes are not equal")
+		t.Fail()
+	}
+}
+
+func TestCodecStream(t *testing.T) {
+	key := newIndexKey()
+	msg := []byte("Test this message and see it stream")
+	reader, err := EncodeStream(bytes.NewReader(msg), key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	newReader, err := DecodeStream(reader, key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	b, err := ioutil.ReadAll(newReader)
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(msg, b) {
+		t.Log("bytes are not equal")
+		t.Fail()
+	}
+}
+
+func TestCodecBuiltin(t *testing.T) {
+	key := make([]byte, 256)
+	for i := range key {
+		key[i] = byte(i * 7)
+	}
+	msg := []byte("Test")
+	encoded, err := Encode(msg, NewBytesKey(key))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	decoded, err := DecodeBytes(encoded, key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !bytes.Equal(msg, decoded) {
+		t.Log("bytes are not equal")
+		t.Fail()
+	}
+}
+
+func TestCodecNotRegistered(t *testing.T) {
+	_, err := Encode([]byte("Test"), unregisteredKey{})
+	if err != errorCodecNotFound {
+		t.Error("expected missing codec error, got:", err)
+	}
+}
+
+type unregisteredKey struct{}
+
+func (unregisteredKey) Version() EncoderInfo {
+	return EncoderInfo{Name: "none", Version: "0.0"}
+}
+
+func (unregisteredKey) CheckValid() (bool, error) {
+	return true, nil
+}
+
+func (unregisteredKey) DictionarySet() string {
+	return "z"
+}
@@ -0,0 +1,105 @@
+package decouplet
+
+import (
+	"context"
+	"io"
+	"math/rand"
+)
+
+// contextBufferSize is the number of bytes copied between checks of a context.
+const contextBufferSize = 1024
+
+// EncodeStreamContext encodes a byte stream against any key with a registered Codec.
+// When ctx is done the stream is closed with ctx.Err() and encoding stops,
+// even if nothing is reading the stream.
+func EncodeStreamContext(
+	ctx context.Context, input io.Reader, key Key, opts ...Option) (*io.PipeReader, error) {
+	codec, err := getCodec(key)
+	if err != nil {
+		return nil, err
+	}
+	return encodeStreamContext(ctx, input, key, codec.Encode, opts...)
+}
+
+// DecodeStreamContext decodes a byte stream against any key with a registered Codec.
+// When ctx is done the stream is closed with ctx.Err() and decoding stops,
+// even if nothing is reading the stream.
+func DecodeStreamContext(ctx context.Context, input io.Reader, key Key) (*io.PipeReader, error) {
+	codec, err := getCodec(key)
+	if err != nil {
+		return nil, err
+	}
+	return decodeStreamContext(ctx, input, key, codec.Groups, codec.Decode)
+}
+
+func encodeStreamContext(
+	ctx context.Context,
+	input io.Reader,
+	key Key,
+	encoder func(byte, Key, *rand.Rand) ([]byte, error),
+	opts ...Option,
+) (*io.PipeReader, error) {
+	if valid, err := key.CheckValid(); !valid {
+		return nil, err
+	}
+	return pipeContext(ctx, func(w io.Writer) error {
+		e := newEncoder(w, key, encoder, opts...)
+		_, err := io.CopyBuffer(
+			e, contextReader{ctx, input}, make([]byte, contextBufferSize))
+		if err != nil {
+			return err
+		}
+		return e.Close()
+	}), nil
+}
+
+func decodeStreamContext(
+	ctx context.Context,
+	input io.Reader,
+	key Key,
+	groups int,
+	decodeFunc func(Key, DecodeGroup) (byte, error),
+) (*io.PipeReader, error) {
+	if valid, err := key.CheckValid(); !valid {
+		return nil, err
+	}
+	return pipeContext(ctx, func(w io.Writer) error {
+		d := newDecoder(contextReader{ctx, input}, key, groups, decodeFunc)
+		_, err := io.CopyBuffer(w, d, make([]byte, contextBufferSize))
+		return err
+	}), nil
+}
+
+// pipeContext runs write in a goroutine, returning a reader of what it writes.
+// When ctx is done the pipe is closed with ctx.Err(), so that write fails
+// instead of blocking while nothing reads the pipe.
+func pipeContext(ctx context.Context, write func(io.Writer) error) *io.PipeReader {
+	reader, writer := io.Pipe()
+	finished := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			writer.CloseWithError(ctx.Err())
+		case <-finished:
+		}
+	}()
+	go func() {
+		err := write(writer)
+		close(finished)
+		writer.CloseWithError(err)
+	}()
+	return reader
+}
+
+// contextReader returns ctx.Err() instead of reading once ctx is done.
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.reader.Read(p)
+}
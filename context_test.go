@@ -0,0 +1,108 @@
+package decouplet
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// waitGoroutines waits for the number of goroutines to fall to n,
+// reporting whether it did within a second.
+func waitGoroutines(n int) bool {
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > n {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}
+
+func TestStreamContext(t *testing.T) {
+	key := vectorBytesKey()
+	msg := []byte("Test this message with a context")
+	ctx := context.Background()
+	reader, err := EncodeBytesStreamContext(ctx, bytes.NewReader(msg), key, WithIntegrity())
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeBytesStreamContext(ctx, reader, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(decoded)
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(msg, b) {
+		t.Error("bytes are not equal")
+	}
+}
+
+func TestEncodeStreamContextCancel(t *testing.T) {
+	img, err := LoadImage("images/test.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	reader, err := EncodeImageStreamContext(
+		ctx, bytes.NewReader(bytes.Repeat([]byte("Test"), 4096)), img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.ReadFull(reader, make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if !waitGoroutines(before) {
+		t.Error("goroutines left running after cancel")
+	}
+	_, err = ioutil.ReadAll(reader)
+	if err != context.Canceled {
+		t.Error("expected canceled, got:", err)
+	}
+}
+
+func TestDecodeStreamContextDeadline(t *testing.T) {
+	key := vectorBytesKey()
+	encoded, err := EncodeBytes(bytes.Repeat([]byte("Test"), 4096), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	reader, err := DecodeBytesStreamContext(ctx, bytes.NewReader(encoded), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nothing reads the stream until the deadline has passed,
+	// so the stream cannot end before it.
+	<-ctx.Done()
+	_, err = ioutil.ReadAll(reader)
+	if err != context.DeadlineExceeded {
+		t.Error("expected deadline exceeded, got:", err)
+	}
+	if !waitGoroutines(before) {
+		t.Error("goroutines left running after deadline")
+	}
+}
+
+func TestStreamContextInvalidKey(t *testing.T) {
+	_, err := EncodeStreamContext(
+		context.Background(), bytes.NewReader(nil), NewBytesKey([]byte("short")))
+	if err != errorByteKeyTooShort {
+		t.Error("expected key too short, got:", err)
+	}
+	_, err = DecodeStreamContext(context.Background(), bytes.NewReader(nil), unregisteredKey{})
+	if err != errorCodecNotFound {
+		t.Error("expected missing codec, got:", err)
+	}
+}
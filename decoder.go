@@ -3,25 +3,102 @@ package decouplet
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"io"
 )
 
+// DecodeError is returned by stream decoders when a byte cannot be decoded.
+// Every byte decoded before it is read from the stream before the error,
+// and nothing after it is decoded.
+type DecodeError struct {
+	// Offset is the position in the encoded stream, counting any header,
+	// where the locations of the byte start.
+	Offset int64
+	// Group is the index of the byte, which is the number of bytes decoded before it.
+	Group int64
+	// Err is the reason the byte could not be decoded.
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decode error at offset %d, group %d: %v", e.Offset, e.Group, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Decode decodes a slice of bytes against any key with a registered Codec.
+func Decode(input []byte, key Key) ([]byte, error) {
+	codec, err := getCodec(key)
+	if err != nil {
+		return nil, err
+	}
+	return decode(input, key, codec.Groups, codec.Decode)
+}
+
+// DecodeStream decodes a byte stream against any key with a registered Codec.
+func DecodeStream(input io.Reader, key Key) (*io.PipeReader, error) {
+	codec, err := getCodec(key)
+	if err != nil {
+		return nil, err
+	}
+	return decodeStream(input, key, codec.Groups, codec.Decode)
+}
+
+// DecodeStreamPartial decodes a byte stream with delimiters
+// against any key with a registered Codec.
+func DecodeStreamPartial(input io.Reader, key Key) (*io.PipeReader, error) {
+	codec, err := getCodec(key)
+	if err != nil {
+		return nil, err
+	}
+	return decodePartialStream(input, key, codec.Groups, codec.Decode)
+}
+
 func decode(
 	input []byte,
-	key encodingKey,
+	key Key,
 	groups int,
-	decodeFunc func(encodingKey, decodeGroup) (byte, error),
+	decodeFunc func(Key, DecodeGroup) (byte, error),
 ) (output []byte, err error) {
-	if valid, err := key.checkValid(); !valid {
+	return decodeDigest(input, key, newKeyDigest(key), groups, decodeFunc)
+}
+
+// decodeDigest decodes a message, sharing a key digest with other messages.
+func decodeDigest(
+	input []byte,
+	key Key,
+	digest *keyDigest,
+	groups int,
+	decodeFunc func(Key, DecodeGroup) (byte, error),
+) (output []byte, err error) {
+	if valid, err := key.CheckValid(); !valid {
 		return nil, err
 	}
 
-	err = key.getVersion().checkEncoder(&input)
+	message := input
+	h, err := checkEncoder(digest, &input)
 	if err != nil {
 		return nil, err
 	}
+	if h.integrity {
+		input, err = checkIntegrity(h.digest, message, input)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if h.format == FormatBinary {
+		decoded := &bytes.Buffer{}
+		err = decodeBinary(
+			bufio.NewReader(bytes.NewReader(input)), key, groups, decodeFunc, decoded)
+		if err != nil {
+			return nil, err
+		}
+		return decoded.Bytes(), nil
+	}
 	decodeGroups, err := findDecodeGroups(
-		input, key.getDictionarySet(), groups)
+		input, dictionarySet(key.DictionarySet()), groups)
 	if err != nil {
 		return nil, err
 	}
@@ -31,113 +108,107 @@ func decode(
 
 func decodeStream(
 	input io.Reader,
-	key encodingKey,
+	key Key,
 	groups int,
-	decodeFunc func(encodingKey, decodeGroup) (byte, error),
+	decodeFunc func(Key, DecodeGroup) (byte, error),
 ) (output *io.PipeReader, err error) {
-	if valid, err := key.checkValid(); !valid {
+	if valid, err := key.CheckValid(); !valid {
 		return nil, err
 	}
 
 	reader, writer := io.Pipe()
-
 	go func() {
-		chars := key.getDictionarySet()
-		defer writer.Close()
-
-		charSplit := splitInfo{chars: chars, groups: groups}
-		scanner := bufio.NewScanner(input)
-		scanner.Split(charSplit.scanDecodeSplit)
-
-		for scanner.Scan() {
-			err := writeDecodeBuffer(decodeFunc, scanner.Bytes(), groups, key, writer)
-			if err != nil {
-				writer.CloseWithError(err)
-			}
-		}
-
-		if err := scanner.Err(); err != nil {
-			writer.CloseWithError(err)
-		}
+		_, err := io.Copy(writer, newDecoder(input, key, groups, decodeFunc))
+		writer.CloseWithError(err)
 	}()
 
 	return reader, nil
 }
 
-func (t splitInfo) scanDecodeSplit(data []byte, atEOF bool) (advance int, token []byte, err error) {
-	if atEOF && len(data) == 0 {
-		return 0, nil, nil
-	}
-	charsFound := 0
-	for i := range data {
-		if t.chars.checkIn(data[i]) {
-			charsFound++
-			if charsFound == t.groups+1 {
-				return i, data[:i], nil
-			}
-		}
-	}
-	if atEOF {
-		return len(data), data, nil
-	}
-	return 0, nil, nil
-}
-
 func decodePartialStream(
 	input io.Reader,
-	key encodingKey,
+	key Key,
 	groups int,
-	decodeFunc func(encodingKey, decodeGroup) (byte, error),
+	decodeFunc func(Key, DecodeGroup) (byte, error),
 ) (output *io.PipeReader, err error) {
-	if valid, err := key.checkValid(); !valid {
+	if valid, err := key.CheckValid(); !valid {
 		return nil, err
 	}
 
 	reader, writer := io.Pipe()
 
 	go func() {
-		defer writer.Close()
-
-		scanner := bufio.NewScanner(input)
-		scanner.Split(scanPartialSplit)
-
-		for scanner.Scan() {
-			scannedSplit := bytes.SplitAfter(scanner.Bytes(), partialStartBytes)
-			if len(scannedSplit) > 0 {
-				skipBytes := bytes.TrimRight(scannedSplit[0], partialStart)
-				skippedReader := bytes.NewReader(skipBytes)
-				_, err = io.Copy(writer, skippedReader)
-				if err != nil {
-					writer.CloseWithError(err)
-				}
-			}
-			if len(scannedSplit) > 1 {
-				encodedReader := bytes.NewReader(scannedSplit[1])
-				reader, err := decodeStream(encodedReader, key, groups, decodeFunc)
-				if err != nil {
-					writer.CloseWithError(err)
-				}
-				_, err = io.Copy(writer, reader)
-				if err != nil {
-					writer.CloseWithError(err)
-				}
-			}
-		}
-
-		if err := scanner.Err(); err != nil {
-			writer.CloseWithError(err)
+		buffered := bufio.NewReader(input)
+		markers, size, err := readPartialPreamble(buffered)
+		if err == nil && size > 0 {
+			err = copyPartialSpans(
+				buffered, markers, int64(size), key, groups, decodeFunc, writer)
+		} else if err == nil {
+			err = copyPartialDecoded(buffered, key, groups, decodeFunc, writer)
 		}
+		writer.CloseWithError(err)
 	}()
 
 	return reader, nil
 }
 
+// copyPartialDecoded writes a stream with delimiters, decoding the parts
+// between them. It stops at the first error, returning a *DecodeError
+// positioned within the whole stream if a part could not be decoded.
+func copyPartialDecoded(
+	input io.Reader,
+	key Key,
+	groups int,
+	decodeFunc func(Key, DecodeGroup) (byte, error),
+	writer io.Writer,
+) error {
+	scanner := bufio.NewScanner(input)
+	scanner.Split(scanPartialSplit)
+
+	digest := newKeyDigest(key)
+	var offset int64
+	var decodedGroups int64
+	for scanner.Scan() {
+		token := scanner.Bytes()
+		scannedSplit := bytes.SplitAfter(token, partialStartBytes)
+		if len(scannedSplit) > 0 {
+			skipBytes := scannedSplit[0]
+			if len(scannedSplit) > 1 {
+				skipBytes = bytes.TrimSuffix(skipBytes, partialStartBytes)
+			}
+			_, err := writer.Write(skipBytes)
+			if err != nil {
+				return err
+			}
+		}
+		if len(scannedSplit) > 1 {
+			encoded := newDecoder(
+				bytes.NewReader(scannedSplit[1]), key, groups, decodeFunc)
+			encoded.digest = digest
+			n, err := io.Copy(writer, encoded)
+			if decodeErr, ok := err.(*DecodeError); ok {
+				return &DecodeError{
+					Offset: offset + int64(len(scannedSplit[0])) + decodeErr.Offset,
+					Group:  decodedGroups + decodeErr.Group,
+					Err:    decodeErr.Err,
+				}
+			}
+			if err != nil {
+				return err
+			}
+			decodedGroups += n
+		}
+		offset += int64(len(token) + len(partialEndBytes))
+	}
+	return scanner.Err()
+}
+
 func scanPartialSplit(data []byte, atEOF bool) (advance int, token []byte, err error) {
 	if atEOF && len(data) == 0 {
 		return 0, nil, nil
 	}
 	if i := bytes.Index(data, partialEndBytes); i >= 0 {
-		return i + len(partialStartBytes), data[:i], nil
+		return i + len(partialEndBytes), data[:i], nil
 	}
 	if atEOF {
 		return len(data), data, nil
@@ -145,39 +216,20 @@ func scanPartialSplit(data []byte, atEOF bool) (advance int, token []byte, err e
 	return 0, nil, nil
 }
 
-func writeDecodeBuffer(
-	decodeFunc func(encodingKey, decodeGroup) (byte, error),
-	buffer []byte,
-	groups int,
-	key encodingKey,
-	writer io.Writer,
-) error {
-	decodeGroups, err := findDecodeGroups(buffer, key.getDictionarySet(), groups)
-	if err != nil {
-		return err
-	}
-	decoded, err := decodeBytes(key, decodeGroups, decodeFunc)
-	if err != nil {
-		return err
-	}
-	_, err = writer.Write(decoded)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
 func findDecodeGroups(
 	input []byte,
 	characters dictionarySet,
 	numGroups int,
-) (decodeGroups []decodeGroup, err error) {
+) (decodeGroups []DecodeGroup, err error) {
+	if len(input) == 0 {
+		return decodeGroups, nil
+	}
 	if !characters.checkIn(input[0]) {
 		return decodeGroups, errorDecodeNotFound
 	}
-	decode := decodeGroup{
-		kind:  []uint8{},
-		place: []string{},
+	decode := DecodeGroup{
+		Kind:  []uint8{},
+		Place: []string{},
 	}
 	buffer := make([]uint8, 0)
 	numberAdded := 0
@@ -185,25 +237,25 @@ func findDecodeGroups(
 	for i := range input {
 		if characters.checkIn(input[i]) {
 			if len(buffer) > 0 {
-				decode.place = append(decode.place, string(buffer))
+				decode.Place = append(decode.Place, string(buffer))
 				buffer = make([]uint8, 0)
 				if numberAdded == numGroups {
 					numberAdded = 0
 					decodeGroups = append(decodeGroups, decode)
-					decode = decodeGroup{
-						kind:  []uint8{},
-						place: []string{},
+					decode = DecodeGroup{
+						Kind:  []uint8{},
+						Place: []string{},
 					}
 				}
 			}
 			if i != len(input)-1 {
-				decode.kind = append(decode.kind, input[i])
+				decode.Kind = append(decode.Kind, input[i])
 				numberAdded++
 			}
 		} else {
 			buffer = append(buffer, input[i])
 			if i == len(input)-1 {
-				decode.place = append(decode.place, string(buffer))
+				decode.Place = append(decode.Place, string(buffer))
 				decodeGroups = append(decodeGroups, decode)
 			}
 		}
@@ -212,9 +264,9 @@ func findDecodeGroups(
 }
 
 func decodeBytes(
-	key encodingKey,
-	decodeGroups []decodeGroup,
-	decodeFunc func(encodingKey, decodeGroup) (byte, error),
+	key Key,
+	decodeGroups []DecodeGroup,
+	decodeFunc func(Key, DecodeGroup) (byte, error),
 ) ([]byte, error) {
 	returnBytes := make([]byte, 0)
 	for i := range decodeGroups {
@@ -0,0 +1,103 @@
+package decouplet
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// corruptGroup replaces the locations of one byte in a text stream
+// with locations outside the key, returning the stream and their offset.
+func corruptGroup(t *testing.T, encoded []byte, set string, group int) ([]byte, int) {
+	start := bytes.IndexByte(encoded, headerEnd) + 1
+	found := 0
+	for i := start; i < len(encoded); i++ {
+		if !strings.ContainsRune(set, rune(encoded[i])) {
+			continue
+		}
+		if found == group*2 {
+			end := i + 1
+			for kinds := 0; end < len(encoded); end++ {
+				if strings.ContainsRune(set, rune(encoded[end])) {
+					kinds++
+					if kinds == 2 {
+						break
+					}
+				}
+			}
+			corrupted := append([]byte(nil), encoded[:i]...)
+			corrupted = append(corrupted, "a99999999b99999999"...)
+			return append(corrupted, encoded[end:]...), i
+		}
+		found++
+	}
+	t.Fatal("group not found")
+	return nil, 0
+}
+
+func TestDecodeStreamError(t *testing.T) {
+	key := vectorBytesKey()
+	reader, err := EncodeBytesStream(
+		bytes.NewReader([]byte("Test")), key, WithFingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupted, offset := corruptGroup(t, encoded, bytesKey(key).DictionarySet(), 2)
+
+	decoded, err := DecodeBytesStream(bytes.NewReader(corrupted), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(decoded)
+	if string(b) != "Te" {
+		t.Error("unexpected bytes before error:", string(b))
+	}
+	decodeErr, ok := err.(*DecodeError)
+	if !ok {
+		t.Fatal("expected decode error, got:", err)
+	}
+	if decodeErr.Offset != int64(offset) || decodeErr.Group != 2 ||
+		decodeErr.Err != errorDecodeGeneric {
+		t.Error("unexpected decode error:", decodeErr)
+	}
+}
+
+func TestDecodeStreamPartialError(t *testing.T) {
+	key := vectorBytesKey()
+	first, err := EncodeBytes([]byte("Hi"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := EncodeBytes([]byte("Test"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first = first[bytes.IndexByte(first, headerEnd)+1:]
+	second = second[bytes.IndexByte(second, headerEnd)+1:]
+	second, offset := corruptGroup(t, second, bytesKey(key).DictionarySet(), 1)
+
+	input := "plain " + partialStart + string(first) + partialEnd +
+		" more " + partialStart + string(second) + partialEnd + " end"
+	offset += strings.LastIndex(input, partialStart) + len(partialStart)
+
+	decoded, err := DecodeBytesStreamPartial(strings.NewReader(input), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(decoded)
+	if string(b) != "plain Hi more T" {
+		t.Error("unexpected bytes before error:", string(b))
+	}
+	decodeErr, ok := err.(*DecodeError)
+	if !ok {
+		t.Fatal("expected decode error, got:", err)
+	}
+	if decodeErr.Offset != int64(offset) || decodeErr.Group != 3 {
+		t.Error("unexpected decode error:", decodeErr)
+	}
+}
@@ -0,0 +1,71 @@
+// Package decouplethttp moves decoupled bodies over HTTP.
+// Handler decodes request bodies, and can encode response bodies,
+// for a handler which reads and writes them as they are,
+// and Transport does the inverse for a client.
+// Bodies are encoded against a key which is a slice of bytes,
+// and named by the encoder and version of the key in HeaderEncoding.
+package decouplethttp
+
+import (
+	"io"
+
+	"github.com/marcsj/decouplet"
+)
+
+// HeaderEncoding names the encoder a body was encoded with, such as "byteec-0.2".
+const HeaderEncoding = "Decouplet-Encoding"
+
+// HeaderAcceptEncoding names the encoder a client can decode a response body with.
+const HeaderAcceptEncoding = "Decouplet-Accept-Encoding"
+
+const headerContentLength = "Content-Length"
+
+type config struct {
+	encodeResponses bool
+	options         []decouplet.Option
+}
+
+// Option configures a Handler or Transport.
+type Option func(*config)
+
+// EncodeResponses makes a Handler encode response bodies
+// for requests naming its encoder in HeaderAcceptEncoding.
+func EncodeResponses() Option {
+	return func(c *config) {
+		c.encodeResponses = true
+	}
+}
+
+// WithOptions sets the options bodies are encoded with.
+func WithOptions(opts ...decouplet.Option) Option {
+	return func(c *config) {
+		c.options = opts
+	}
+}
+
+func newConfig(opts []Option) config {
+	c := config{}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// encoderName returns the name of the encoder of a key, as written in headers.
+func encoderName(key []byte) string {
+	info := decouplet.NewBytesKey(key).Version()
+	return info.Name + "-" + info.Version
+}
+
+// body reads a decoded or encoded body, closing the body it was read from when closed.
+type body struct {
+	io.Reader
+	source io.Closer
+}
+
+func (b body) Close() error {
+	if closer, ok := b.Reader.(io.Closer); ok {
+		closer.Close()
+	}
+	return b.source.Close()
+}
@@ -0,0 +1,122 @@
+package decouplethttp
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/marcsj/decouplet"
+)
+
+// Handler returns a handler which decodes the bodies of requests
+// naming the encoder of key in HeaderEncoding before calling next.
+// Requests without the header are passed to next as they are,
+// and requests naming another encoder are refused with 415 Unsupported Media Type.
+// An error decoding a body is returned to next when reading it.
+// With EncodeResponses, response bodies are encoded against key
+// for requests naming its encoder in HeaderAcceptEncoding.
+func Handler(key []byte, next http.Handler, opts ...Option) http.Handler {
+	c := newConfig(opts)
+	name := encoderName(key)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if encoding := r.Header.Get(HeaderEncoding); encoding != "" {
+			if encoding != name {
+				http.Error(w, decouplet.ErrEncoderVersion.Error(), http.StatusUnsupportedMediaType)
+				return
+			}
+			bytesKey := decouplet.NewBytesKey(key)
+			if valid, err := bytesKey.CheckValid(); !valid {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			// Decode as next reads, so nothing is left running
+			// if it does not read the whole body.
+			r = r.Clone(r.Context())
+			r.Body = body{Reader: decouplet.NewDecoder(r.Body, bytesKey), source: r.Body}
+			r.ContentLength = -1
+			r.Header.Del(HeaderEncoding)
+			r.Header.Del(headerContentLength)
+		}
+		if !c.encodeResponses || r.Header.Get(HeaderAcceptEncoding) != name {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if valid, err := decouplet.NewBytesKey(key).CheckValid(); !valid {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		e := &encodingWriter{ResponseWriter: w, key: key, name: name, options: c.options}
+		next.ServeHTTP(e, r)
+		e.close(r)
+	})
+}
+
+// encodingWriter encodes the body of a response as it is written.
+type encodingWriter struct {
+	http.ResponseWriter
+	key     []byte
+	name    string
+	options []decouplet.Option
+	encoder io.WriteCloser
+	status  int
+}
+
+func (e *encodingWriter) WriteHeader(status int) {
+	if status < http.StatusOK {
+		e.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if e.status != 0 {
+		return
+	}
+	e.status = status
+	e.Header().Set(HeaderEncoding, e.name)
+	e.Header().Del(headerContentLength)
+	e.ResponseWriter.WriteHeader(status)
+}
+
+func (e *encodingWriter) Write(p []byte) (int, error) {
+	if e.status == 0 {
+		e.WriteHeader(http.StatusOK)
+	}
+	if e.encoder == nil {
+		e.encoder = decouplet.NewEncoder(
+			e.ResponseWriter, decouplet.NewBytesKey(e.key), e.options...)
+	}
+	return e.encoder.Write(p)
+}
+
+// Flush sends what has been encoded so far to the client,
+// if the wrapped ResponseWriter is an http.Flusher.
+func (e *encodingWriter) Flush() {
+	if e.status == 0 {
+		e.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := e.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the wrapped ResponseWriter, for http.ResponseController.
+func (e *encodingWriter) Unwrap() http.ResponseWriter {
+	return e.ResponseWriter
+}
+
+// close ends the encoded body, if the response has one.
+// If it cannot be ended the response is aborted,
+// so the client does not take a truncated body as whole.
+func (e *encodingWriter) close(r *http.Request) {
+	if e.status == 0 {
+		e.WriteHeader(http.StatusOK)
+	}
+	if r.Method == http.MethodHead || e.status == http.StatusNoContent ||
+		e.status == http.StatusNotModified {
+		return
+	}
+	if e.encoder == nil {
+		e.encoder = decouplet.NewEncoder(
+			e.ResponseWriter, decouplet.NewBytesKey(e.key), e.options...)
+	}
+	if err := e.encoder.Close(); err != nil {
+		panic(http.ErrAbortHandler)
+	}
+}
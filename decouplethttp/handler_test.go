@@ -0,0 +1,188 @@
+package decouplethttp
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcsj/decouplet"
+)
+
+func testKey() []byte {
+	key := make([]byte, 256)
+	for i := range key {
+		key[i] = byte(i*37 + 11)
+	}
+	return key
+}
+
+// echo writes the body of a request back in upper case.
+var echo = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.Header.Get(HeaderEncoding) != "" {
+		http.Error(w, "encoding header was not removed", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write(bytes.ToUpper(b))
+})
+
+func TestHandlerDecodesRequests(t *testing.T) {
+	key := testKey()
+	encoded, err := decouplet.EncodeBytes([]byte("hello"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := Handler(key, echo)
+
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(encoded))
+	r.Header.Set(HeaderEncoding, encoderName(key))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Body.String() != "HELLO" {
+		t.Error("unexpected response:", w.Code, w.Body.String())
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("plain"))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Body.String() != "PLAIN" {
+		t.Error("unexpected response without encoding:", w.Code, w.Body.String())
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(encoded))
+	r.Header.Set(HeaderEncoding, "imgec-0.2")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Error("expected other encoder to be refused:", w.Code)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("a1b2zz"))
+	r.Header.Set(HeaderEncoding, encoderName(key))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Error("expected decode error to reach handler:", w.Code)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(encoded))
+	r.Header.Set(HeaderEncoding, encoderName(key[:8]))
+	w = httptest.NewRecorder()
+	Handler(key[:8], echo).ServeHTTP(w, r)
+	if w.Code != http.StatusInternalServerError {
+		t.Error("expected invalid key to fail:", w.Code)
+	}
+}
+
+func TestHandlerUnreadBody(t *testing.T) {
+	key := testKey()
+	encoded, err := decouplet.EncodeBytes(bytes.Repeat([]byte("hello "), 1000), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ignore := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	handler := Handler(key, ignore)
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 20; i++ {
+		r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(encoded))
+		r.Header.Set(HeaderEncoding, encoderName(key))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusNoContent {
+			t.Fatal("unexpected response:", w.Code)
+		}
+	}
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if runtime.NumGoroutine() > before {
+		t.Error("goroutines left running after the handler ignored the body")
+	}
+}
+
+func TestHandlerEncodesResponses(t *testing.T) {
+	key := testKey()
+	handler := Handler(key, echo,
+		EncodeResponses(), WithOptions(decouplet.WithIntegrity()))
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("hello"))
+	r.Header.Set(HeaderAcceptEncoding, encoderName(key))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Header().Get(HeaderEncoding) != encoderName(key) {
+		t.Fatal("response is not named as encoded:", w.Header())
+	}
+	decoded, err := decouplet.DecodeBytes(w.Body.Bytes(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != "HELLO" {
+		t.Error("unexpected decoded response:", string(decoded))
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("hello"))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Header().Get(HeaderEncoding) != "" || w.Body.String() != "HELLO" {
+		t.Error("response was encoded without being asked for:", w.Body.String())
+	}
+
+	empty := Handler(key, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), EncodeResponses(), WithOptions(decouplet.WithIntegrity()))
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(HeaderAcceptEncoding, encoderName(key))
+	w = httptest.NewRecorder()
+	empty.ServeHTTP(w, r)
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Error("unexpected body for no content:", w.Code, w.Body.String())
+	}
+}
+
+// failingWriter is a ResponseWriter which cannot write a body.
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestHandlerResponseWriter(t *testing.T) {
+	key := testKey()
+	flushing := Handler(key, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("part"))
+		w.(http.Flusher).Flush()
+	}), EncodeResponses())
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(HeaderAcceptEncoding, encoderName(key))
+	w := httptest.NewRecorder()
+	flushing.ServeHTTP(w, r)
+	if !w.Flushed {
+		t.Error("response was not flushed")
+	}
+
+	empty := Handler(key, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		EncodeResponses(), WithOptions(decouplet.WithIntegrity()))
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Error("expected response to be aborted, got:", recovered)
+		}
+	}()
+	empty.ServeHTTP(failingWriter{httptest.NewRecorder()}, r)
+}
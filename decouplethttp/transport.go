@@ -0,0 +1,78 @@
+package decouplethttp
+
+import (
+	"net/http"
+
+	"github.com/marcsj/decouplet"
+)
+
+// Transport is an http.RoundTripper which encodes request bodies against Key,
+// naming its encoder in HeaderEncoding, and asks for response bodies
+// encoded against Key, decoding those which are.
+type Transport struct {
+	// Key is the key bodies are encoded and decoded against.
+	Key []byte
+	// Base makes requests, or http.DefaultTransport if it is nil.
+	Base   http.RoundTripper
+	config config
+}
+
+// NewTransport returns a Transport making requests with base.
+func NewTransport(key []byte, base http.RoundTripper, opts ...Option) *Transport {
+	return &Transport{
+		Key:    key,
+		Base:   base,
+		config: newConfig(opts),
+	}
+}
+
+// RoundTrip encodes the body of a request, makes it, and decodes the body of its response.
+// A response naming another encoder is closed, and decouplet.ErrEncoderVersion returned.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	name := encoderName(t.Key)
+	if valid, err := decouplet.NewBytesKey(t.Key).CheckValid(); !valid {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	out := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		encoded, err := decouplet.EncodeBytesStream(req.Body, t.Key, t.config.options...)
+		if err != nil {
+			req.Body.Close()
+			return nil, err
+		}
+		out.Body = body{Reader: encoded, source: req.Body}
+		out.ContentLength = -1
+		out.GetBody = nil
+		out.Header.Set(HeaderEncoding, name)
+		out.Header.Del(headerContentLength)
+	}
+	out.Header.Set(HeaderAcceptEncoding, name)
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	encoding := resp.Header.Get(HeaderEncoding)
+	if encoding == "" {
+		return resp, nil
+	}
+	if encoding != name {
+		resp.Body.Close()
+		return nil, decouplet.ErrEncoderVersion
+	}
+	// Decode as the caller reads, so nothing is left running
+	// if it closes the body before reading all of it.
+	decoded := decouplet.NewDecoder(resp.Body, decouplet.NewBytesKey(t.Key))
+	resp.Body = body{Reader: decoded, source: resp.Body}
+	resp.ContentLength = -1
+	resp.Header.Del(HeaderEncoding)
+	resp.Header.Del(headerContentLength)
+	return resp, nil
+}
@@ -0,0 +1,127 @@
+package decouplethttp
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcsj/decouplet"
+)
+
+func TestTransport(t *testing.T) {
+	key := testKey()
+	var wire string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		wire = string(b)
+		r.Body = ioutil.NopCloser(strings.NewReader(wire))
+		Handler(key, echo, EncodeResponses(),
+			WithOptions(decouplet.WithFingerprint())).ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport(key, nil,
+		WithOptions(decouplet.WithFormat(decouplet.FormatBinary)))}
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader("hello over http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || string(b) != "HELLO OVER HTTP" {
+		t.Error("unexpected response:", resp.StatusCode, string(b))
+	}
+	if strings.Contains(wire, "hello") {
+		t.Error("request body was not encoded:", wire)
+	}
+	if resp.Header.Get(HeaderEncoding) != "" {
+		t.Error("encoding header was not removed")
+	}
+
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != http.StatusOK || len(b) != 0 {
+		t.Error("unexpected response without body:", resp.StatusCode, string(b), err)
+	}
+}
+
+func TestTransportErrors(t *testing.T) {
+	key := testKey()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderEncoding, "imgec-0.2")
+		w.Write([]byte("r1g2"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport(key, nil)}
+	_, err := client.Get(server.URL)
+	if err == nil || !strings.Contains(err.Error(), decouplet.ErrEncoderVersion.Error()) {
+		t.Error("expected other encoder to be refused, got:", err)
+	}
+
+	client = &http.Client{Transport: NewTransport(key[:8], nil)}
+	_, err = client.Post(server.URL, "text/plain", strings.NewReader("hello"))
+	if err == nil {
+		t.Error("expected invalid key to fail")
+	}
+}
+
+// roundTripper makes requests with a function.
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestTransportUnreadBody(t *testing.T) {
+	key := testKey()
+	encoded, err := decouplet.EncodeBytes(bytes.Repeat([]byte("hello "), 1000), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := roundTripper(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{}
+		header.Set(HeaderEncoding, encoderName(key))
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     header,
+			Body:       ioutil.NopCloser(bytes.NewReader(encoded)),
+			Request:    req,
+		}, nil
+	})
+	client := &http.Client{Transport: NewTransport(key, base)}
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 20; i++ {
+		resp, err := client.Get("http://example.com/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := make([]byte, 5)
+		_, err = io.ReadFull(resp.Body, b)
+		if err != nil || string(b) != "hello" {
+			t.Error("unexpected body:", string(b), err)
+		}
+		resp.Body.Close()
+	}
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if runtime.NumGoroutine() > before {
+		t.Error("goroutines left running after the body was closed")
+	}
+}
@@ -3,131 +3,251 @@ package decouplet
 import (
 	"bufio"
 	"bytes"
+	"hash"
 	"io"
-	"io/ioutil"
+	"math/rand"
 )
 
-type encodingKey interface {
-	getVersion() encoderInfo
-	checkValid() (bool, error)
-	getDictionarySet() dictionarySet
-	getDictionary() dictionary
-	checkVariance() int
+// Key is implemented by types which can be used as an encoding key.
+// A Key is paired with the Codec registered under the name
+// returned by its Version.
+type Key interface {
+	// Version returns the encoder information written to message headers.
+	Version() EncoderInfo
+	// CheckValid reports whether the key can be used for encoding.
+	CheckValid() (bool, error)
+	// DictionarySet returns the characters which begin each encoded location.
+	DictionarySet() string
 }
 
-func encode(
-	input []byte,
-	key encodingKey,
-	encoder func(byte, encodingKey) ([]byte, error),
-) ([]byte, error) {
-	if valid, err := key.checkValid(); !valid {
+// Encode encodes a slice of bytes against any key with a registered Codec.
+func Encode(input []byte, key Key, opts ...Option) ([]byte, error) {
+	codec, err := getCodec(key)
+	if err != nil {
 		return nil, err
 	}
+	return encode(input, key, codec.Encode, opts...)
+}
 
-	b, err := key.getVersion().writeVersion()
+// EncodeStream encodes a byte stream against any key with a registered Codec.
+func EncodeStream(input io.Reader, key Key, opts ...Option) (*io.PipeReader, error) {
+	codec, err := getCodec(key)
 	if err != nil {
 		return nil, err
 	}
-	reader, err := encodeStream(bytes.NewReader(input), key, encoder)
+	return encodeStream(input, key, codec.Encode, opts...)
+}
+
+// EncodeStreamPartial encodes a byte stream partially against any key with a registered Codec.
+// Arguments take and skip are used to determine how many bytes to take, and skip along a stream.
+func EncodeStreamPartial(
+	input io.Reader, key Key, take int, skip int, opts ...Option) (*io.PipeReader, error) {
+	codec, err := getCodec(key)
 	if err != nil {
 		return nil, err
 	}
+	return encodePartialStream(input, key, take, skip, codec.Encode, opts...)
+}
+
+func encode(
+	input []byte,
+	key Key,
+	encoder func(byte, Key, *rand.Rand) ([]byte, error),
+	opts ...Option,
+) ([]byte, error) {
+	if valid, err := key.CheckValid(); !valid {
+		return nil, err
+	}
+	config := newOptions(opts)
+	random := config.newRandom()
 
-	output, err := ioutil.ReadAll(reader)
+	h, err := newHeader(key, config, random)
+	if err != nil {
+		return nil, err
+	}
+	b, err := h.writeVersion()
 	if err != nil {
 		return nil, err
 	}
-	err = reader.Close()
+	output := bytes.NewBuffer(b)
+	err = writeEncoded(bytes.NewReader(input), output, key, encoder, config, random)
 	if err != nil {
 		return nil, err
 	}
-	return append(b, output...), nil
+	if h.integrity {
+		mac, err := newIntegrityMAC(h.digest)
+		if err != nil {
+			return nil, err
+		}
+		mac.Write(output.Bytes())
+		output.Write(integrityTrailer(mac))
+	}
+	return output.Bytes(), nil
 }
 
 func encodeStream(
 	input io.Reader,
-	key encodingKey,
-	encoder func(byte, encodingKey) ([]byte, error),
+	key Key,
+	encoder func(byte, Key, *rand.Rand) ([]byte, error),
+	opts ...Option,
 ) (*io.PipeReader, error) {
-	if valid, err := key.checkValid(); !valid {
+	if valid, err := key.CheckValid(); !valid {
+		return nil, err
+	}
+	config := newOptions(opts)
+	random := config.newRandom()
+	h, err := newHeader(key, config, random)
+	if err != nil {
 		return nil, err
 	}
+	var mac hash.Hash
+	if h.integrity {
+		mac, err = newIntegrityMAC(h.digest)
+		if err != nil {
+			return nil, err
+		}
+	}
 	reader, writer := io.Pipe()
 	go func(
 		input io.Reader,
 		writer *io.PipeWriter,
-		encoder func(byte, encodingKey) ([]byte, error),
-		key encodingKey) {
-
-		scanner := bufio.NewScanner(input)
-		scanner.Split(bufio.ScanBytes)
+		encoder func(byte, Key, *rand.Rand) ([]byte, error),
+		key Key) {
 
-		for scanner.Scan() {
-			m, err := encoder(scanner.Bytes()[0], key)
-			if err != nil {
-				writer.CloseWithError(err)
-				return
+		var body io.Writer = writer
+		if mac != nil {
+			body = io.MultiWriter(writer, mac)
+		}
+		if h.written() {
+			b, err := h.writeVersion()
+			if err == nil {
+				_, err = body.Write(b)
 			}
-			_, err = writer.Write(m)
 			if err != nil {
 				writer.CloseWithError(err)
 				return
 			}
 		}
-
-		if err := scanner.Err(); err != nil {
-			writer.CloseWithError(err)
-			return
+		err := writeEncoded(input, body, key, encoder, config, random)
+		if err == nil && mac != nil {
+			_, err = writer.Write(integrityTrailer(mac))
 		}
-		writer.Close()
+		writer.CloseWithError(err)
 	}(input, writer, encoder, key)
 
 	return reader, nil
 }
 
+func writeEncoded(
+	input io.Reader,
+	writer io.Writer,
+	key Key,
+	encoder func(byte, Key, *rand.Rand) ([]byte, error),
+	config options,
+	random random,
+) error {
+	if config.workers > 1 {
+		return writeEncodedParallel(input, writer, key, encoder, config, random)
+	}
+	set := dictionarySet(key.DictionarySet())
+	packed := make([]byte, 0)
+
+	scanner := bufio.NewScanner(input)
+	scanner.Split(bufio.ScanBytes)
+
+	for scanner.Scan() {
+		m, err := encoder(scanner.Bytes()[0], key, random.Rand)
+		if err == nil {
+			err = random.err()
+		}
+		if err != nil {
+			return err
+		}
+		if config.format == FormatBinary {
+			packed, err = packLocations(packed[:0], m, set)
+			if err != nil {
+				return err
+			}
+			m = packed
+		}
+		_, err = writer.Write(m)
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
 func encodePartialStream(
 	input io.Reader,
-	key encodingKey,
+	key Key,
 	take int,
 	skip int,
-	encoder func(byte, encodingKey) ([]byte, error),
+	encoder func(byte, Key, *rand.Rand) ([]byte, error),
+	opts ...Option,
 ) (*io.PipeReader, error) {
-	reader, writer := io.Pipe()
-	if valid, err := key.checkValid(); !valid {
+	if valid, err := key.CheckValid(); !valid {
 		return nil, err
 	}
-
+	if take <= 0 {
+		return nil, errorPartialTake
+	}
+	if skip < 0 {
+		return nil, errorPartialSkip
+	}
+	partial, err := newPartialWriter(nil, key, encoder, opts)
+	if err != nil {
+		return nil, err
+	}
+	reader, writer := io.Pipe()
 	go func() {
-		defer writer.Close()
-		for {
-			_, err := writer.Write(partialStartBytes)
-			if err != nil {
-				writer.CloseWithError(err)
-				return
-			}
-			takeR := io.LimitReader(input, int64(take))
-			encodedR, err := encodeStream(takeR, key, encoder)
-			if err != nil {
-				writer.CloseWithError(err)
-				return
-			}
-			_, err = io.Copy(writer, encodedR)
-			if err != nil {
-				writer.CloseWithError(err)
-				return
-			}
-			_, err = writer.Write(partialEndBytes)
-			if err != nil {
-				writer.CloseWithError(err)
-				return
+		writer.CloseWithError(copyPartial(input, take, skip, partial, writer))
+	}()
+	return reader, nil
+}
+
+// copyPartial writes a partial stream, encoding take bytes in turn
+// and leaving skip bytes after each as they are.
+func copyPartial(input io.Reader, take int, skip int, partial *partialWriter, writer io.Writer) error {
+	output := bufio.NewWriter(writer)
+	partial.writer = output
+	err := partial.writePreamble()
+	if err != nil {
+		return err
+	}
+	taken := make([]byte, take)
+	skipped := make([]byte, skip)
+	for {
+		n, err := io.ReadFull(input, taken)
+		if n > 0 {
+			writeErr := partial.writeSpan(taken[:n])
+			if writeErr != nil {
+				return writeErr
 			}
-			_, err = io.CopyN(writer, input, int64(skip))
-			if err != nil {
-				writer.CloseWithError(err)
-				return
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		n, err = io.ReadFull(input, skipped)
+		if n > 0 {
+			writeErr := partial.writePlain(skipped[:n])
+			if writeErr != nil {
+				return writeErr
 			}
 		}
-	}()
-
-	return reader, nil
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	err = partial.flush()
+	if err != nil {
+		return err
+	}
+	return output.Flush()
 }
@@ -0,0 +1,275 @@
+package decouplet
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+)
+
+func init() {
+	RegisterCodec(audioKey{}.Version().Name, Codec{
+		Groups: 2,
+		Encode: findAudioPattern,
+		Decode: getAudioDefs,
+	})
+}
+
+type audioKey struct {
+	*Audio
+}
+
+const matchFindRetriesAudio = 4
+const minAudioKeyFrames = 4096
+const audioCheckedMax = 255
+const audioDictionaryChars = "abcdefghijklmnopqrstuvwxyz"
+
+var errorAudioKeyTooShort error = keyError("key needs at least 4096 audio frames")
+var errorAudioKeyChannels error = keyError("key has too many channels")
+var errorAudioKeyFormat error = keyError("key is not 8, 16 or 24-bit audio with a channel")
+
+func (audioKey) Version() EncoderInfo {
+	return EncoderInfo{
+		Name:    "audec",
+		Version: "0.1",
+	}
+}
+
+func (k audioKey) checkVariance() int {
+	levelMap := map[byte]bool{}
+	for i := 0; i < k.Frames(); i++ {
+		for _, level := range k.levels(i) {
+			levelMap[level] = true
+		}
+	}
+	return int((float32(len(levelMap)) / audioCheckedMax) * 100)
+}
+
+func (k audioKey) CheckValid() (bool, error) {
+	if k.Audio == nil {
+		return false, errorAudioKeyTooShort
+	}
+	switch k.BitsPerSample {
+	case 8, 16, 24:
+	default:
+		return false, errorAudioKeyFormat
+	}
+	if k.Channels < 1 {
+		return false, errorAudioKeyFormat
+	}
+	if k.Channels > len(audioDictionaryChars) {
+		return false, errorAudioKeyChannels
+	}
+	if k.Frames() < minAudioKeyFrames {
+		return false, errorAudioKeyTooShort
+	}
+	return true, nil
+}
+
+func (k audioKey) DictionarySet() string {
+	return audioDictionaryChars[:k.Channels]
+}
+
+func (k audioKey) WriteMaterial(w io.Writer) error {
+	_, err := w.Write(k.Data)
+	return err
+}
+
+func (k audioKey) getDictionary() dictionary {
+	decoders := make([]decodeRef, k.Channels)
+	for i := range decoders {
+		decoders[i] = decodeRef{
+			character: audioDictionaryChars[i],
+			amount:    0,
+		}
+	}
+	return dictionary{
+		decoders: decoders,
+	}
+}
+
+// dictionaryAmplitudes returns a copy of dict holding the level
+// of each channel in a frame, in the order they are stored.
+func dictionaryAmplitudes(levels []byte, dict dictionary) dictionary {
+	decoders := make([]decodeRef, len(dict.decoders))
+	for i := range dict.decoders {
+		decoders[i] = decodeRef{
+			character: dict.decoders[i].character,
+			amount:    levels[i],
+		}
+	}
+	return dictionary{
+		decoders: decoders,
+	}
+}
+
+// NewAudioKey returns a Key for use with Encode and Decode from audio.
+func NewAudioKey(key *Audio) Key {
+	return audioKey{key}
+}
+
+// EncodeAudio encodes a slice of bytes against an audio key.
+func EncodeAudio(input []byte, key *Audio, opts ...Option) ([]byte, error) {
+	return encode(
+		input, audioKey{key}, findAudioPattern, opts...)
+}
+
+// EncodeAudioStream encodes a stream of bytes against an audio key.
+func EncodeAudioStream(input io.Reader, key *Audio, opts ...Option) (*io.PipeReader, error) {
+	return encodeStream(
+		input, audioKey{key}, findAudioPattern, opts...)
+}
+
+// EncodeAudioStreamPartial encodes a byte stream partially against an audio key.
+// Arguments take and skip are used to determine how many bytes to take, and skip along a stream.
+func EncodeAudioStreamPartial(
+	input io.Reader, key *Audio, take int, skip int, opts ...Option) (*io.PipeReader, error) {
+	return encodePartialStream(
+		input, audioKey{key}, take, skip, findAudioPattern, opts...)
+}
+
+// DecodeAudio decodes a slice of bytes against an audio key.
+func DecodeAudio(input []byte, key *Audio) ([]byte, error) {
+	return decode(
+		input, audioKey{key}, 2, getAudioDefs)
+}
+
+// DecodeAudioStream decodes a stream of bytes against an audio key.
+func DecodeAudioStream(input io.Reader, key *Audio) (*io.PipeReader, error) {
+	return decodeStream(
+		input, audioKey{key}, 2, getAudioDefs)
+}
+
+// DecodeAudioStreamPartial decodes a byte stream with delimiters against an audio key.
+func DecodeAudioStreamPartial(input io.Reader, key *Audio) (*io.PipeReader, error) {
+	return decodePartialStream(
+		input, audioKey{key}, 2, getAudioDefs)
+}
+
+func getAudioDefs(key Key, group DecodeGroup) (byte, error) {
+	if len(group.Place) < 2 {
+		return 0, errorDecodeGroup
+	}
+	audio, ok := key.(audioKey)
+	if !ok {
+		return 0, errorKeyCastFailed
+	}
+	dict := audio.getDictionary()
+
+	loc1, err := strconv.Atoi(group.Place[0])
+	if err != nil {
+		return 0, err
+	}
+	loc2, err := strconv.Atoi(group.Place[1])
+	if err != nil {
+		return 0, err
+	}
+	if loc1 < 0 || loc1 >= audio.Frames() || loc2 < 0 || loc2 >= audio.Frames() {
+		return 0, errorDecodeGeneric
+	}
+
+	dict1 := dictionaryAmplitudes(audio.levels(loc1), dict)
+	dict2 := dictionaryAmplitudes(audio.levels(loc2), dict)
+
+	var change1 uint8
+	var change2 uint8
+	found := 0
+	for _, g := range dict1.decoders {
+		if g.character == group.Kind[0] {
+			change1 = g.amount
+			found++
+		}
+	}
+	for _, g := range dict2.decoders {
+		if g.character == group.Kind[1] {
+			change2 = g.amount
+			found++
+		}
+	}
+	if found != 2 {
+		return 0, errorDecodeNotFound
+	}
+	return change2 - change1, nil
+}
+
+func findAudioPattern(char byte, key Key, random *rand.Rand) ([]byte, error) {
+	audioKey, ok := key.(audioKey)
+	if !ok {
+		return nil, errorKeyCastFailed
+	}
+	var pattern []byte
+	var err error
+
+	for i := 0; i < matchFindRetriesAudio; i++ {
+		pattern, err = getAudioPattern(char, audioKey, random)
+		if err == nil {
+			return pattern, nil
+		}
+	}
+
+	return nil, err
+}
+
+func getAudioPattern(char byte, key audioKey, random *rand.Rand) ([]byte, error) {
+	bounds := key.Frames()
+	current := random.Intn(bounds)
+	startFinding := random.Intn(bounds)
+	dictionary := key.getDictionary()
+	currentDict := dictionaryAmplitudes(key.levels(current), dictionary)
+
+	var pattern []byte
+	var err error
+
+	if startFinding > bounds/2 {
+		for x := startFinding; x >= 0; x-- {
+			pattern, err = findAudioPartner(current, x, char, currentDict, key)
+			if err == nil {
+				return pattern, nil
+			}
+		}
+	} else {
+		for x := startFinding; x < bounds; x++ {
+			pattern, err = findAudioPartner(current, x, char, currentDict, key)
+			if err == nil {
+				return pattern, nil
+			}
+		}
+	}
+
+	return nil, err
+}
+
+func findAudioPartner(
+	current int,
+	checked int,
+	difference byte,
+	currentDict dictionary,
+	key audioKey) ([]byte, error) {
+	checkedDict := dictionaryAmplitudes(key.levels(checked), currentDict)
+	if match, firstType, secondType := checkAmplitudeMatch(
+		difference, currentDict, checkedDict); match {
+		return []byte(fmt.Sprintf(
+			"%s%v%s%v",
+			string(firstType), current,
+			string(secondType), checked)), nil
+	}
+
+	return nil, errorMatchNotFound
+}
+
+func checkAmplitudeMatch(
+	diff byte,
+	current dictionary,
+	checked dictionary) (bool, uint8, uint8) {
+	for v := range current.decoders {
+		for k := range checked.decoders {
+			if checked.decoders[k].amount ==
+				current.decoders[v].amount+uint8(diff) {
+				return true,
+					current.decoders[v].character,
+					checked.decoders[k].character
+			}
+		}
+	}
+	return false, 0, 0
+}
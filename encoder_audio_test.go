@@ -0,0 +1,172 @@
+package decouplet
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+)
+
+func makeWAV(t *testing.T, channels int, bits int, frames int) []byte {
+	data := make([]byte, frames*channels*bits/8)
+	_, err := rand.Read(data)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	buf := &bytes.Buffer{}
+	buf.WriteString("RIFF")
+	binary.Write(buf, binary.LittleEndian, uint32(36+len(data)))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(buf, binary.LittleEndian, uint32(16))
+	binary.Write(buf, binary.LittleEndian, uint16(wavFormatPCM))
+	binary.Write(buf, binary.LittleEndian, uint16(channels))
+	binary.Write(buf, binary.LittleEndian, uint32(44100))
+	binary.Write(buf, binary.LittleEndian, uint32(44100*channels*bits/8))
+	binary.Write(buf, binary.LittleEndian, uint16(channels*bits/8))
+	binary.Write(buf, binary.LittleEndian, uint16(bits))
+	buf.WriteString("data")
+	binary.Write(buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func TestReadAudio(t *testing.T) {
+	audio, err := ReadAudio(bytes.NewReader(makeWAV(t, 2, 16, 5000)))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if audio.Channels != 2 || audio.BitsPerSample != 16 || audio.SampleRate != 44100 {
+		t.Error("unexpected format:", audio.Channels, audio.BitsPerSample, audio.SampleRate)
+	}
+	if audio.Frames() != 5000 {
+		t.Error("unexpected frame count:", audio.Frames())
+	}
+	_, err = ReadAudio(bytes.NewReader([]byte("RIFF0000WAVX")))
+	if err != errorWAVHeader {
+		t.Error("expected header error, got:", err)
+	}
+}
+
+func TestAudioLevels(t *testing.T) {
+	audio := &Audio{Channels: 2, BitsPerSample: 16, Data: []byte{0x00, 0x00, 0xff, 0x7f}}
+	levels := audio.levels(0)
+	if !bytes.Equal(levels, []byte{0x80, 0xff}) {
+		t.Error("unexpected 16-bit levels:", levels)
+	}
+	audio = &Audio{Channels: 2, BitsPerSample: 8, Data: []byte{0x80, 0xff}}
+	levels = audio.levels(0)
+	if !bytes.Equal(levels, []byte{0x80, 0xff}) {
+		t.Error("unexpected 8-bit levels:", levels)
+	}
+	if len(NewAudioKey(audio).DictionarySet()) != audio.Channels {
+		t.Error("dictionary is not sized by channels")
+	}
+}
+
+func TestAudioMessage(t *testing.T) {
+	formats := []struct {
+		channels int
+		bits     int
+	}{
+		{1, 8},
+		{2, 16},
+		{1, 24},
+		{6, 24},
+	}
+	originalMessage :=
+		"!!**_-+Test THIS bigger message with More Symbols" +
+			"@$_()#$%^#@!~#2364###$%! *(#$%)^@#%$@"
+	for _, f := range formats {
+		audio, err := ReadAudio(bytes.NewReader(makeWAV(t, f.channels, f.bits, 8000)))
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		newMessage, err := EncodeAudio([]byte(originalMessage), audio)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		message, err := DecodeAudio(newMessage, audio)
+		if err != nil {
+			t.Error(err)
+		}
+		if originalMessage != string(message) {
+			t.Log("message not equal for format:", f.channels, f.bits)
+			t.Fail()
+		}
+	}
+}
+
+func TestAudioKeyTooShort(t *testing.T) {
+	audio, err := ReadAudio(bytes.NewReader(makeWAV(t, 1, 16, 100)))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	_, err = EncodeAudio([]byte("Test"), audio)
+	if err != errorAudioKeyTooShort {
+		t.Error("expected key too short error, got:", err)
+	}
+	for _, audio := range []*Audio{{}, {Channels: 1, BitsPerSample: 12}} {
+		_, err = EncodeAudio([]byte("Test"), audio)
+		if err != errorAudioKeyFormat {
+			t.Error("expected key format error, got:", err)
+		}
+	}
+}
+
+func TestEncodeAudioConcurrent(t *testing.T) {
+	audio, err := ReadAudio(bytes.NewReader(makeWAV(t, 2, 16, 8000)))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	msg := []byte("Test this message and see it stream")
+	reader, err := EncodeAudioStream(bytes.NewReader(msg), audio)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	newReader, err := DecodeAudioStream(reader, audio)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	b, err := ioutil.ReadAll(newReader)
+	t.Log(string(b))
+	if !bytes.Equal(msg, b) {
+		t.Log("bytes are not equal")
+		t.Fail()
+	}
+}
+
+func TestEncodeAudioConcurrentPartial(t *testing.T) {
+	audio, err := ReadAudio(bytes.NewReader(makeWAV(t, 2, 16, 8000)))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	take := 1
+	skip := 3
+	msg := []byte("Test this message and see it stream, using partial encoding.")
+	reader, err := EncodeAudioStreamPartial(bytes.NewReader(msg), audio, take, skip)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	newReader, err := DecodeAudioStreamPartial(reader, audio)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	b, err := ioutil.ReadAll(newReader)
+	t.Log(string(b))
+	if !bytes.Equal(msg, b) {
+		t.Log("bytes are not equal")
+		t.Fail()
+	}
+}
@@ -1,16 +1,19 @@
 package decouplet
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"io"
 	"math/rand"
 	"strconv"
-	"time"
 )
 
 func init() {
-	rand.Seed(time.Now().Unix())
+	RegisterCodec(bytesKey(nil).Version().Name, Codec{
+		Groups: 2,
+		Encode: findBytePattern,
+		Decode: getByteDefs,
+	})
 }
 
 type byteChecked struct {
@@ -20,20 +23,27 @@ type byteChecked struct {
 
 type bytesKey []byte
 
+// byteSource provides the values of a key which uses byte deltas,
+// whether they are held in memory or read as they are needed.
+type byteSource interface {
+	length() int64
+	byteAt(loc int64) (byte, error)
+}
+
 const matchFindRetriesByte = 16
 const minByteKeySize = 64
 const byteCheckedMax = 255
 
-var errorByteKeyTooShort = errors.New("key is smaller than minimum length of 64 bytes")
+var errorByteKeyTooShort error = keyError("key is smaller than minimum length of 64 bytes")
 
-func (bytesKey) getVersion() encoderInfo {
-	return encoderInfo{
+func (bytesKey) Version() EncoderInfo {
+	return EncoderInfo{
 		Name:    "byteec",
 		Version: "0.2",
 	}
 }
 
-func (k bytesKey) checkValid() (bool, error) {
+func (k bytesKey) CheckValid() (bool, error) {
 	if len(k) < minByteKeySize {
 		return false, errorByteKeyTooShort
 	}
@@ -48,8 +58,21 @@ func (k bytesKey) checkVariance() int {
 	return int((float32(len(charMap)) / byteCheckedMax) * 100)
 }
 
-func (bytesKey) getDictionarySet() dictionarySet {
-	return dictionarySet("abcdefghijk")
+func (k bytesKey) length() int64 {
+	return int64(len(k))
+}
+
+func (k bytesKey) byteAt(loc int64) (byte, error) {
+	return k[loc], nil
+}
+
+func (k bytesKey) WriteMaterial(w io.Writer) error {
+	_, err := w.Write(k)
+	return err
+}
+
+func (bytesKey) DictionarySet() string {
+	return "abcdefghijk"
 }
 
 func (bytesKey) getDictionary() dictionary {
@@ -103,23 +126,61 @@ func (bytesKey) getDictionary() dictionary {
 	}
 }
 
+// NewBytesKey returns a Key for use with Encode and Decode from a slice of bytes.
+func NewBytesKey(key []byte) Key {
+	return bytesKey(key)
+}
+
 // EncodeBytes encodes a slice of bytes against a key which is a slice of bytes.
-func EncodeBytes(input []byte, key []byte) ([]byte, error) {
+func EncodeBytes(input []byte, key []byte, opts ...Option) ([]byte, error) {
 	return encode(
-		input, bytesKey(key), findBytePattern)
+		input, bytesKey(key), findBytePattern, opts...)
 }
 
 // EncodeBytesStream encodes a byte stream against a key which is a slice of bytes.
-func EncodeBytesStream(input io.Reader, key []byte) (*io.PipeReader, error) {
+func EncodeBytesStream(input io.Reader, key []byte, opts ...Option) (*io.PipeReader, error) {
 	return encodeStream(
-		input, bytesKey(key), findBytePattern)
+		input, bytesKey(key), findBytePattern, opts...)
+}
+
+// EncodeBytesStreamContext encodes a stream of bytes against a key which is a slice of bytes,
+// stopping and closing the stream with ctx.Err() when ctx is done.
+func EncodeBytesStreamContext(
+	ctx context.Context, input io.Reader, key []byte, opts ...Option) (*io.PipeReader, error) {
+	return encodeStreamContext(
+		ctx, input, bytesKey(key), findBytePattern, opts...)
 }
 
 // EncodeBytesStreamPartial encodes a byte stream partially against a key which is a slice of bytes.
 // Arguments take and skip are used to determine how many bytes to take, and skip along a stream.
-func EncodeBytesStreamPartial(input io.Reader, key []byte, take int, skip int) (*io.PipeReader, error) {
+func EncodeBytesStreamPartial(
+	input io.Reader, key []byte, take int, skip int, opts ...Option) (*io.PipeReader, error) {
 	return encodePartialStream(
-		input, bytesKey(key), take, skip, findBytePattern)
+		input, bytesKey(key), take, skip, findBytePattern, opts...)
+}
+
+// EncodeBytesStreamMatched encodes the regions of a byte stream selected by match
+// against a key which is a slice of bytes, leaving the rest as it is.
+// The output is decoded with DecodeBytesStreamPartial.
+func EncodeBytesStreamMatched(
+	input io.Reader, key []byte, match Matcher, opts ...Option) (*io.PipeReader, error) {
+	return encodeMatchedStream(
+		input, bytesKey(key), match, findBytePattern, opts...)
+}
+
+// EncodeBytesJSON encodes the values of a JSON document at paths
+// against a key which is a slice of bytes, leaving the rest as it is.
+// The output is decoded with DecodeBytesJSON.
+func EncodeBytesJSON(input []byte, key []byte, paths []string, opts ...Option) ([]byte, error) {
+	return encodeJSON(
+		input, bytesKey(key), paths, findBytePattern, opts...)
+}
+
+// DecodeBytesJSON decodes the values of a JSON document
+// against a key which is a slice of bytes.
+func DecodeBytesJSON(input []byte, key []byte) ([]byte, error) {
+	return decodeJSON(
+		input, bytesKey(key), 2, getByteDefs)
 }
 
 // DecodeBytes decodes a slice of bytes against a key which is a slice of bytes.
@@ -134,6 +195,14 @@ func DecodeBytesStream(input io.Reader, key []byte) (*io.PipeReader, error) {
 		input, bytesKey(key), 2, getByteDefs)
 }
 
+// DecodeBytesStreamContext decodes a stream of bytes against a key which is a slice of bytes,
+// stopping and closing the stream with ctx.Err() when ctx is done.
+func DecodeBytesStreamContext(
+	ctx context.Context, input io.Reader, key []byte) (*io.PipeReader, error) {
+	return decodeStreamContext(
+		ctx, input, bytesKey(key), 2, getByteDefs)
+}
+
 // DecodeBytesStreamPartial decodes a byte stream with delimiters
 // against a key which is a slice of bytes.
 func DecodeBytesStreamPartial(input io.Reader, key []byte) (*io.PipeReader, error) {
@@ -142,13 +211,16 @@ func DecodeBytesStreamPartial(input io.Reader, key []byte) (*io.PipeReader, erro
 }
 
 // AnalyzeBytesKey takes a slice of bytes and analyzes its scale of usefulness at encoding.
+//
+// Deprecated: use ReportBytesKey, which also reports why a key is weak.
 func AnalyzeBytesKey(key []byte) (scale int) {
 	dict := bytesKey(key).getDictionary()
+	random := options{}.newRandom()
 	found := 0.0
 	for i := 0; i < 255; i++ {
 		perByte := 0.0
 		for j := 0; j < matchFindRetriesByte; j++ {
-			randByte := key[rand.Intn(len(key))]
+			randByte := key[random.Intn(len(key))]
 			for k := 0; k < len(key); k++ {
 				success, _, _ := checkByteMatch(byte(i), randByte, key[k], dict)
 				if success {
@@ -162,21 +234,37 @@ func AnalyzeBytesKey(key []byte) (scale int) {
 	return int(float64(found) / 255.0)
 }
 
-func getByteDefs(key encodingKey, group decodeGroup) (byte, error) {
-	if len(group.place) < 2 {
-		return 0, errorDecodeGroup
-	}
-	bytes, ok := key.(bytesKey)
+func getByteDefs(key Key, group DecodeGroup) (byte, error) {
+	source, ok := key.(byteSource)
 	if !ok {
 		return 0, errorKeyCastFailed
 	}
-	dict := key.getDictionary()
+	return readByteDefs(source, bytesKey(nil).getDictionary(), group)
+}
+
+// readByteDefs decodes a group against a source of values,
+// which is shared by keys using byte deltas.
+func readByteDefs(source byteSource, dict dictionary, group DecodeGroup) (byte, error) {
+	if len(group.Place) < 2 {
+		return 0, errorDecodeGroup
+	}
 
-	loc1, err := strconv.Atoi(group.place[0])
+	loc1, err := strconv.ParseInt(group.Place[0], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	loc2, err := strconv.ParseInt(group.Place[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if loc1 < 0 || loc1 >= source.length() || loc2 < 0 || loc2 >= source.length() {
+		return 0, errorDecodeGeneric
+	}
+	value1, err := source.byteAt(loc1)
 	if err != nil {
 		return 0, err
 	}
-	loc2, err := strconv.Atoi(group.place[1])
+	value2, err := source.byteAt(loc2)
 	if err != nil {
 		return 0, err
 	}
@@ -184,65 +272,76 @@ func getByteDefs(key encodingKey, group decodeGroup) (byte, error) {
 	var change1 uint8
 	var change2 uint8
 	for _, g := range dict.decoders {
-		if g.character == group.kind[0] {
-			if len(bytes) >= loc1 {
-				change1 = bytes[loc1] + g.amount
-			} else {
-				return 0, errorDecodeGeneric
-			}
+		if g.character == group.Kind[0] {
+			change1 = value1 + g.amount
 		}
 	}
 	for _, g := range dict.decoders {
-		if g.character == group.kind[1] {
-			if len(bytes) >= loc2 {
-				change2 = bytes[loc2] + g.amount
-			} else {
-				return 0, errorDecodeGeneric
-			}
+		if g.character == group.Kind[1] {
+			change2 = value2 + g.amount
 		}
 	}
 	return change2 - change1, nil
 }
 
-func findBytePattern(char byte, key encodingKey) ([]byte, error) {
-	bytesKey, ok := key.(bytesKey)
+func findBytePattern(char byte, key Key, random *rand.Rand) ([]byte, error) {
+	if prepared, ok := key.(*PreparedBytesKey); ok {
+		return prepared.pickPattern(char, random)
+	}
+	source, ok := key.(byteSource)
 	if !ok {
 		return nil, errorKeyCastFailed
 	}
+	return matchBytePattern(char, source, bytesKey(nil).getDictionary(), random)
+}
+
+// matchBytePattern encodes a byte against a source of values,
+// which is shared by keys using byte deltas.
+func matchBytePattern(
+	char byte,
+	source byteSource,
+	dictionary dictionary,
+	random *rand.Rand) ([]byte, error) {
 	pattern := make([]byte, 0)
 	var err error
 
 	for i := 0; i < matchFindRetriesByte; i++ {
-		pattern, err = getBytePattern(char, bytesKey)
-		if err == nil {
-			return pattern, nil
+		pattern, err = getBytePattern(char, source, dictionary, random)
+		if err != errorMatchNotFound {
+			return pattern, err
 		}
 	}
 
 	return nil, err
 }
 
-func getBytePattern(char byte, key bytesKey) ([]byte, error) {
-	bounds := len(key)
-	current := rand.Intn(bounds)
-	startFinding := rand.Intn(bounds)
-	dictionary := key.getDictionary()
+func getBytePattern(
+	char byte,
+	key byteSource,
+	dictionary dictionary,
+	random *rand.Rand) ([]byte, error) {
+	bounds := key.length()
+	current := random.Int63n(bounds)
+	startFinding := random.Int63n(bounds)
+	currentValue, err := key.byteAt(current)
+	if err != nil {
+		return nil, err
+	}
 
 	var pattern []byte
-	var err error
 
 	if startFinding > bounds/2 {
 		for x := startFinding; x >= 0; x-- {
-			pattern, err = findBytePartner(current, x, char, key, dictionary)
-			if err == nil {
-				return pattern, nil
+			pattern, err = findBytePartner(current, x, currentValue, char, key, dictionary)
+			if err != errorMatchNotFound {
+				return pattern, err
 			}
 		}
 	} else {
 		for x := startFinding; x < bounds; x++ {
-			pattern, err = findBytePartner(current, x, char, key, dictionary)
-			if err == nil {
-				return pattern, nil
+			pattern, err = findBytePartner(current, x, currentValue, char, key, dictionary)
+			if err != errorMatchNotFound {
+				return pattern, err
 			}
 		}
 	}
@@ -251,13 +350,18 @@ func getBytePattern(char byte, key bytesKey) ([]byte, error) {
 }
 
 func findBytePartner(
-	current int,
-	checked int,
+	current int64,
+	checked int64,
+	currentValue byte,
 	difference byte,
-	bytes []byte,
+	key byteSource,
 	dict dictionary) ([]byte, error) {
+	checkedValue, err := key.byteAt(checked)
+	if err != nil {
+		return nil, err
+	}
 	if match, firstType, secondType := checkByteMatch(
-		difference, bytes[current], bytes[checked], dict); match {
+		difference, currentValue, checkedValue, dict); match {
 		return []byte(fmt.Sprintf(
 			"%s%v%s%v",
 			string(firstType), current,
@@ -0,0 +1,116 @@
+package decouplet
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+)
+
+// PreparedBytesKey is a bytes key with an index of where each value
+// can be found, so that a location pair for a byte is picked directly
+// instead of by scanning the key. Messages are compatible with those
+// encoded against a bytes key holding the same data.
+//
+// A PreparedBytesKey is not modified after it is made,
+// and is safe for concurrent use.
+type PreparedBytesKey struct {
+	key bytesKey
+	// positions holds the locations of each value in the key.
+	positions [256][]int64
+	// pairs holds, for each difference, the pairs of values
+	// in the key which are that difference apart.
+	pairs [256][][2]byte
+	// choices holds, for each byte, the dictionary kinds and
+	// the difference between values which together encode it.
+	choices [256][]byteChoice
+}
+
+type byteChoice struct {
+	first      uint8
+	second     uint8
+	difference byte
+}
+
+// PrepareBytesKey copies and indexes a key which is a slice of bytes.
+// It is worth preparing a key which will encode many bytes.
+func PrepareBytesKey(key []byte) (*PreparedBytesKey, error) {
+	k := make(bytesKey, len(key))
+	copy(k, key)
+	if valid, err := k.CheckValid(); !valid {
+		return nil, err
+	}
+
+	prepared := &PreparedBytesKey{key: k}
+	for i, b := range k {
+		prepared.positions[b] = append(prepared.positions[b], int64(i))
+	}
+	for v1 := 0; v1 < 256; v1++ {
+		for v2 := 0; v2 < 256; v2++ {
+			if prepared.positions[v1] == nil || prepared.positions[v2] == nil {
+				continue
+			}
+			difference := byte(v2 - v1)
+			prepared.pairs[difference] = append(
+				prepared.pairs[difference], [2]byte{byte(v1), byte(v2)})
+		}
+	}
+
+	dict := k.getDictionary()
+	for _, first := range dict.decoders {
+		for _, second := range dict.decoders {
+			for c := 0; c < 256; c++ {
+				difference := byte(c) - (second.amount - first.amount)
+				if prepared.pairs[difference] == nil {
+					continue
+				}
+				prepared.choices[c] = append(prepared.choices[c], byteChoice{
+					first:      first.character,
+					second:     second.character,
+					difference: difference,
+				})
+			}
+		}
+	}
+	return prepared, nil
+}
+
+func (k *PreparedBytesKey) Version() EncoderInfo {
+	return k.key.Version()
+}
+
+func (k *PreparedBytesKey) CheckValid() (bool, error) {
+	return k.key.CheckValid()
+}
+
+func (k *PreparedBytesKey) DictionarySet() string {
+	return k.key.DictionarySet()
+}
+
+func (k *PreparedBytesKey) WriteMaterial(w io.Writer) error {
+	return k.key.WriteMaterial(w)
+}
+
+func (k *PreparedBytesKey) length() int64 {
+	return k.key.length()
+}
+
+func (k *PreparedBytesKey) byteAt(loc int64) (byte, error) {
+	return k.key.byteAt(loc)
+}
+
+// pickPattern returns a random pair of locations encoding char.
+func (k *PreparedBytesKey) pickPattern(char byte, random *rand.Rand) ([]byte, error) {
+	choices := k.choices[char]
+	if len(choices) == 0 {
+		return nil, errorMatchNotFound
+	}
+	choice := choices[random.Intn(len(choices))]
+	pairs := k.pairs[choice.difference]
+	pair := pairs[random.Intn(len(pairs))]
+	current := k.positions[pair[0]][random.Intn(len(k.positions[pair[0]]))]
+	checked := k.positions[pair[1]][random.Intn(len(k.positions[pair[1]]))]
+	return []byte(fmt.Sprintf(
+		"%s%v%s%v",
+		string(choice.first), current,
+		string(choice.second), checked)), nil
+}
@@ -0,0 +1,148 @@
+package decouplet
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+	"sync"
+	"testing"
+)
+
+func TestPreparedBytesMessage(t *testing.T) {
+	key := make([]byte, 4096)
+	_, err := rand.Read(key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	prepared, err := PrepareBytesKey(key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	msg := make([]byte, 256)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	encoded, err := Encode(msg, prepared, WithFingerprint())
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	decoded, err := DecodeBytes(encoded, key)
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(msg, decoded) {
+		t.Log("bytes key did not decode prepared key message")
+		t.Fail()
+	}
+	encoded, err = EncodeBytes(msg, key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	decoded, err = Decode(encoded, prepared)
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(msg, decoded) {
+		t.Log("prepared key did not decode bytes key message")
+		t.Fail()
+	}
+}
+
+func TestPreparedBytesCopy(t *testing.T) {
+	key := vectorBytesKey()
+	prepared, err := PrepareBytesKey(key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	original := append([]byte(nil), key...)
+	for i := range key {
+		key[i] = 0
+	}
+	encoded, err := Encode([]byte("Test"), prepared)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	decoded, err := DecodeBytes(encoded, original)
+	if err != nil || string(decoded) != "Test" {
+		t.Error("prepared key changed with the slice it was made from")
+	}
+}
+
+func TestPreparedBytesUnreachable(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, minByteKeySize)
+	prepared, err := PrepareBytesKey(key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	_, err = Encode([]byte{11}, prepared)
+	if err != errorMatchNotFound {
+		t.Error("expected match not found, got:", err)
+	}
+	_, err = PrepareBytesKey(key[:minByteKeySize-1])
+	if err != errorByteKeyTooShort {
+		t.Error("expected key too short, got:", err)
+	}
+}
+
+func TestPreparedBytesConcurrent(t *testing.T) {
+	prepared, err := PrepareBytesKey(vectorBytesKey())
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	msg := []byte("Test this message against a shared prepared key")
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reader, err := EncodeStream(bytes.NewReader(msg), prepared)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			decoded, err := DecodeStream(reader, prepared)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			b, err := ioutil.ReadAll(decoded)
+			if err != nil {
+				t.Error(err)
+			}
+			if !bytes.Equal(msg, b) {
+				t.Error("bytes are not equal")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// benchmarkBytesKey returns a large key with few distinct values,
+// for which finding a partner location takes the longest.
+func benchmarkBytesKey() []byte {
+	key := make([]byte, 1<<16)
+	for i := range key {
+		key[i] = byte(i*7) & 0xf0
+	}
+	return key
+}
+
+func BenchmarkEncodeBytes(b *testing.B) {
+	benchmarkEncodeStream(b, NewBytesKey(benchmarkBytesKey()), 1<<12)
+}
+
+func BenchmarkEncodePreparedBytes(b *testing.B) {
+	prepared, err := PrepareBytesKey(benchmarkBytesKey())
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkEncodeStream(b, prepared, 1<<12)
+}
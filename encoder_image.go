@@ -1,6 +1,7 @@
 package decouplet
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"image"
@@ -8,11 +9,14 @@ import (
 	"io"
 	"math/rand"
 	"strconv"
-	"time"
 )
 
 func init() {
-	rand.Seed(time.Now().Unix())
+	RegisterCodec(imageKey{}.Version().Name, Codec{
+		Groups: 2,
+		Encode: findPixelPattern,
+		Decode: getImgDefs,
+	})
 }
 
 type imageKey struct {
@@ -23,10 +27,10 @@ const matchFindRetriesImage = 4
 const imageKeySize = 300
 const imageCheckedMax = 46368
 
-var errorImageKeyTooSmall = errors.New("key needs to be larger than 300x300")
+var errorImageKeyTooSmall error = keyError("key needs to be larger than 300x300")
 
-func (imageKey) getVersion() encoderInfo {
-	return encoderInfo{
+func (imageKey) Version() EncoderInfo {
+	return EncoderInfo{
 		Name:    "imgec",
 		Version: "0.2",
 	}
@@ -42,15 +46,32 @@ func (k imageKey) checkVariance() int {
 	return int((float32(len(colorMap)) / imageCheckedMax) * 100)
 }
 
-func (k imageKey) checkValid() (bool, error) {
+func (k imageKey) CheckValid() (bool, error) {
 	if k.Image.Bounds().Max.X < imageKeySize || k.Image.Bounds().Max.Y < imageKeySize {
 		return false, errorImageKeyTooSmall
 	}
 	return true, nil
 }
 
-func (imageKey) getDictionarySet() dictionarySet {
-	return dictionarySet("rgbacmyk")
+func (imageKey) DictionarySet() string {
+	return "rgbacmyk"
+}
+
+func (k imageKey) WriteMaterial(w io.Writer) error {
+	bounds := k.Bounds()
+	row := make([]byte, 0, bounds.Dx()*4)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row = row[:0]
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := k.At(x, y).RGBA()
+			row = append(row, uint8(r), uint8(g), uint8(b), uint8(a))
+		}
+		_, err := w.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (imageKey) getDictionary() dictionary {
@@ -118,24 +139,37 @@ func dictionaryRGBACMYK(col color.Color, dict dictionary) dictionary {
 	return dict
 }
 
+// NewImageKey returns a Key for use with Encode and Decode from an image.
+func NewImageKey(key image.Image) Key {
+	return imageKey{key}
+}
+
 // EncodeImage encodes a slice of bytes against an image key.
-func EncodeImage(input []byte, key image.Image) ([]byte, error) {
+func EncodeImage(input []byte, key image.Image, opts ...Option) ([]byte, error) {
 	return encode(
-		input, imageKey{key}, findPixelPattern)
-	return nil, nil
+		input, imageKey{key}, findPixelPattern, opts...)
 }
 
 // EncodeImageStream encodes a stream of bytes against an image key.
-func EncodeImageStream(input io.Reader, key image.Image) (*io.PipeReader, error) {
+func EncodeImageStream(input io.Reader, key image.Image, opts ...Option) (*io.PipeReader, error) {
 	return encodeStream(
-		input, imageKey{key}, findPixelPattern)
+		input, imageKey{key}, findPixelPattern, opts...)
+}
+
+// EncodeImageStreamContext encodes a stream of bytes against an image key,
+// stopping and closing the stream with ctx.Err() when ctx is done.
+func EncodeImageStreamContext(
+	ctx context.Context, input io.Reader, key image.Image, opts ...Option) (*io.PipeReader, error) {
+	return encodeStreamContext(
+		ctx, input, imageKey{key}, findPixelPattern, opts...)
 }
 
 // EncodeImageStreamPartial encodes a byte stream partially against an image key.
 // Arguments take and skip are used to determine how many bytes to take, and skip along a stream.
-func EncodeImageStreamPartial(input io.Reader, key image.Image, take int, skip int) (*io.PipeReader, error) {
+func EncodeImageStreamPartial(
+	input io.Reader, key image.Image, take int, skip int, opts ...Option) (*io.PipeReader, error) {
 	return encodePartialStream(
-		input, imageKey{key}, take, skip, findPixelPattern)
+		input, imageKey{key}, take, skip, findPixelPattern, opts...)
 }
 
 // DecodeImage encodes a slice of bytes against an image key.
@@ -150,30 +184,41 @@ func DecodeImageStream(input io.Reader, key image.Image) (*io.PipeReader, error)
 		input, imageKey{key}, 2, getImgDefs)
 }
 
+// DecodeImageStreamContext decodes a stream of bytes against an image key,
+// stopping and closing the stream with ctx.Err() when ctx is done.
+func DecodeImageStreamContext(
+	ctx context.Context, input io.Reader, key image.Image) (*io.PipeReader, error) {
+	return decodeStreamContext(
+		ctx, input, imageKey{key}, 2, getImgDefs)
+}
+
 // DecodeImageStreamPartial decodes a byte stream with delimiters against an image key.
 func DecodeImageStreamPartial(input io.Reader, key image.Image) (*io.PipeReader, error) {
 	return decodePartialStream(
 		input, imageKey{key}, 2, getImgDefs)
 }
 
-func getImgDefs(key encodingKey, group decodeGroup) (byte, error) {
-	if len(group.place) < 2 {
+func getImgDefs(key Key, group DecodeGroup) (byte, error) {
+	if len(group.Place) < 2 {
 		return 0, errors.New("decode group missing locations")
 	}
-	img, ok := key.(imageKey)
-	if !ok {
-		return 0, errors.New("failed to cast key")
-	}
-	dict := key.getDictionary()
-
-	loc1, err := strconv.Atoi(group.place[0])
+	loc1, err := strconv.Atoi(group.Place[0])
 	if err != nil {
 		return 0, err
 	}
-	loc2, err := strconv.Atoi(group.place[1])
+	loc2, err := strconv.Atoi(group.Place[1])
 	if err != nil {
 		return 0, err
 	}
+	if prepared, ok := preparedImage(key); ok {
+		return prepared.readDefs(group, loc2)
+	}
+	img, ok := key.(imageKey)
+	if !ok {
+		return 0, errors.New("failed to cast key")
+	}
+	dict := img.getDictionary()
+
 	location1, err := getXYLocation(loc1, img.Bounds().Max.X)
 	if err != nil {
 		return 0, err
@@ -191,19 +236,22 @@ func getImgDefs(key encodingKey, group decodeGroup) (byte, error) {
 	dict2 := dictionaryRGBACMYK(changeColor2, dict)
 
 	for _, g := range dict1.decoders {
-		if g.character == group.kind[0] {
+		if g.character == group.Kind[0] {
 			change1 = g.amount
 		}
 	}
 	for _, g := range dict2.decoders {
-		if g.character == group.kind[1] {
+		if g.character == group.Kind[1] {
 			change2 = g.amount
 		}
 	}
 	return change2 - change1, nil
 }
 
-func findPixelPattern(char byte, key encodingKey) ([]byte, error) {
+func findPixelPattern(char byte, key Key, random *rand.Rand) ([]byte, error) {
+	if prepared, ok := preparedImage(key); ok {
+		return prepared.pickPattern(char, random)
+	}
 	imageKey, ok := key.(imageKey)
 	if !ok {
 		return nil, errorKeyCastFailed
@@ -212,7 +260,7 @@ func findPixelPattern(char byte, key encodingKey) ([]byte, error) {
 	var err error
 
 	for i := 0; i < matchFindRetriesImage; i++ {
-		pattern, err = getPixelPattern(char, imageKey)
+		pattern, err = getPixelPattern(char, imageKey, random)
 		if err == nil {
 			return pattern, nil
 		}
@@ -221,12 +269,12 @@ func findPixelPattern(char byte, key encodingKey) ([]byte, error) {
 	return nil, err
 }
 
-func getPixelPattern(char byte, key imageKey) ([]byte, error) {
+func getPixelPattern(char byte, key imageKey, random *rand.Rand) ([]byte, error) {
 	bounds := key.Bounds()
-	currentX := rand.Intn(bounds.Max.X)
-	currentY := rand.Intn(bounds.Max.Y)
-	startX := rand.Intn(bounds.Max.X)
-	startY := rand.Intn(bounds.Max.Y)
+	currentX := random.Intn(bounds.Max.X)
+	currentY := random.Intn(bounds.Max.Y)
+	startX := random.Intn(bounds.Max.X)
+	startY := random.Intn(bounds.Max.Y)
 	dictionary := key.getDictionary()
 
 	pattern := make([]byte, 0)
@@ -0,0 +1,177 @@
+package decouplet
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"math/rand"
+)
+
+// preparedImageCandidates is the most pixels indexed for each byte,
+// which bounds the memory used by the index of a large image.
+const preparedImageCandidates = 1 << 14
+
+// preparedImageIndexSeed seeds the choice of pixels indexed when there are
+// more than preparedImageCandidates, so the index of an image is always the same.
+const preparedImageIndexSeed = 1
+
+// PreparedImageKey is an image key which holds its channel levels in flat planes,
+// with an index of the pixels able to encode each byte, so that encoding
+// and decoding need no color conversions. Messages are compatible with
+// those encoded against the image it was prepared from.
+//
+// A PreparedImageKey is an image.Image, and may be passed to EncodeImage and
+// DecodeImage, or used as a Key. It is not modified after it is made,
+// and is safe for concurrent use.
+type PreparedImageKey struct {
+	image.Image
+	width  int
+	height int
+	// planes holds the level of every pixel for each character
+	// of the dictionary, in the order of DictionarySet.
+	planes [][]uint8
+	// index holds, for each byte, pixels whose levels differ by it.
+	index [256][]uint32
+}
+
+// PrepareImageKey converts an image into channel planes and indexes it.
+// It is worth preparing a key which will encode many bytes.
+func PrepareImageKey(img image.Image) (*PreparedImageKey, error) {
+	if valid, err := (imageKey{img}).CheckValid(); !valid {
+		return nil, err
+	}
+	bounds := img.Bounds()
+	prepared := &PreparedImageKey{
+		Image:  img,
+		width:  bounds.Max.X,
+		height: bounds.Max.Y,
+	}
+
+	prepared.planes = imagePlanes(img, prepared.width, prepared.height)
+	pixels := prepared.width * prepared.height
+
+	random := rand.New(rand.NewSource(preparedImageIndexSeed))
+	var seen [256]int64
+	for n := 0; n < pixels; n++ {
+		reachable := reachableLevels(prepared.planes, n)
+		for char := range reachable {
+			if !reachable[char] {
+				continue
+			}
+			seen[char]++
+			if len(prepared.index[char]) < preparedImageCandidates {
+				prepared.index[char] = append(prepared.index[char], uint32(n))
+			} else if i := random.Int63n(seen[char]); i < preparedImageCandidates {
+				prepared.index[char][i] = uint32(n)
+			}
+		}
+	}
+	return prepared, nil
+}
+
+// imagePlanes returns the level of every pixel of an image for each character
+// of the image dictionary, indexed by pixel number.
+func imagePlanes(img image.Image, width int, height int) [][]uint8 {
+	dict := imageKey{img}.getDictionary()
+	planes := make([][]uint8, len(dict.decoders))
+	for i := range planes {
+		planes[i] = make([]uint8, width*height)
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			levels := dictionaryRGBACMYK(img.At(x, y), dict)
+			n := getPixelNumber(x, y, width)
+			for i := range levels.decoders {
+				planes[i][n] = levels.decoders[i].amount
+			}
+		}
+	}
+	return planes
+}
+
+// reachableLevels returns which bytes can be encoded
+// by the difference between two levels of a pixel.
+func reachableLevels(planes [][]uint8, pixel int) [256]bool {
+	var reachable [256]bool
+	for _, first := range planes {
+		for _, second := range planes {
+			reachable[second[pixel]-first[pixel]] = true
+		}
+	}
+	return reachable
+}
+
+func (k *PreparedImageKey) Version() EncoderInfo {
+	return imageKey{k.Image}.Version()
+}
+
+func (k *PreparedImageKey) CheckValid() (bool, error) {
+	return imageKey{k.Image}.CheckValid()
+}
+
+func (k *PreparedImageKey) DictionarySet() string {
+	return imageKey{k.Image}.DictionarySet()
+}
+
+func (k *PreparedImageKey) WriteMaterial(w io.Writer) error {
+	return imageKey{k.Image}.WriteMaterial(w)
+}
+
+// preparedImage returns the prepared image held by a key, if it has one.
+func preparedImage(key Key) (*PreparedImageKey, bool) {
+	switch k := key.(type) {
+	case *PreparedImageKey:
+		return k, true
+	case imageKey:
+		prepared, ok := k.Image.(*PreparedImageKey)
+		return prepared, ok
+	}
+	return nil, false
+}
+
+// level returns the level of a pixel for a dictionary character,
+// which is zero for characters not in the dictionary.
+func (k *PreparedImageKey) level(pixel int, character uint8) uint8 {
+	set := k.DictionarySet()
+	for i := range set {
+		if set[i] == character {
+			return k.planes[i][pixel]
+		}
+	}
+	return 0
+}
+
+// pickPattern returns a random pair of locations encoding char.
+// As with images which are not prepared, the byte is the difference
+// between two levels of the second pixel.
+func (k *PreparedImageKey) pickPattern(char byte, random *rand.Rand) ([]byte, error) {
+	candidates := k.index[char]
+	if len(candidates) == 0 {
+		return nil, errorMatchNotFound
+	}
+	checked := int(candidates[random.Intn(len(candidates))])
+	current := random.Intn(k.width * k.height)
+
+	set := k.DictionarySet()
+	matches := make([][2]uint8, 0, len(set)*len(set))
+	for v := range k.planes {
+		for c := range k.planes {
+			if k.planes[c][checked]-k.planes[v][checked] == char {
+				matches = append(matches, [2]uint8{set[v], set[c]})
+			}
+		}
+	}
+	match := matches[random.Intn(len(matches))]
+	return []byte(fmt.Sprintf(
+		"%s%v%s%v",
+		string(match[0]), current,
+		string(match[1]), checked)), nil
+}
+
+// readDefs decodes a group from the levels of its second pixel.
+func (k *PreparedImageKey) readDefs(group DecodeGroup, loc2 int) (byte, error) {
+	if loc2 < 0 || loc2 >= k.width*k.height {
+		return 0, errorDecodeGeneric
+	}
+	return k.level(loc2, group.Kind[1]) - k.level(loc2, group.Kind[0]), nil
+}
@@ -0,0 +1,121 @@
+package decouplet
+
+import (
+	"bytes"
+	"image"
+	"io/ioutil"
+	"sync"
+	"testing"
+)
+
+func TestPreparedImageMessage(t *testing.T) {
+	img, err := LoadImage("images/test.jpg")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	prepared, err := PrepareImageKey(img)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	msg := make([]byte, 256)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	encoded, err := EncodeImage(msg, prepared)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	decoded, err := DecodeImage(encoded, img)
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(msg, decoded) {
+		t.Log("image key did not decode prepared key message")
+		t.Fail()
+	}
+	encoded, err = EncodeImage(msg, img)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	decoded, err = Decode(encoded, prepared)
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(msg, decoded) {
+		t.Log("prepared key did not decode image key message")
+		t.Fail()
+	}
+}
+
+func TestPreparedImageSynthetic(t *testing.T) {
+	img := vectorImageKey()
+	prepared, err := PrepareImageKey(img)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	msg := []byte("Test this message against a prepared synthetic image")
+	encoded, err := Encode(msg, prepared, WithFormat(FormatBinary), WithFingerprint())
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	decoded, err := Decode(encoded, NewImageKey(img))
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(msg, decoded) {
+		t.Log("bytes are not equal")
+		t.Fail()
+	}
+	_, err = PrepareImageKey(image.NewRGBA(image.Rect(0, 0, 10, 10)))
+	if err != errorImageKeyTooSmall {
+		t.Error("expected key too small, got:", err)
+	}
+}
+
+func TestPreparedImageConcurrent(t *testing.T) {
+	prepared, err := PrepareImageKey(vectorImageKey())
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	msg := []byte("Test this message against a shared prepared image")
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reader, err := EncodeImageStream(bytes.NewReader(msg), prepared)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			decoded, err := DecodeImageStream(reader, prepared)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			b, err := ioutil.ReadAll(decoded)
+			if err != nil {
+				t.Error(err)
+			}
+			if !bytes.Equal(msg, b) {
+				t.Error("bytes are not equal")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkEncodePreparedImageStream(b *testing.B) {
+	prepared, err := PrepareImageKey(vectorImageKey())
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkEncodeStream(b, prepared, 1<<10)
+}
@@ -0,0 +1,127 @@
+package decouplet
+
+import (
+	"container/list"
+	"io"
+	"sync"
+)
+
+// readerAtKey is a bytes key which reads its values from an io.ReaderAt
+// as they are needed, instead of holding the whole key in memory.
+// Messages are compatible with those encoded against a bytes key
+// holding the same data.
+type readerAtKey struct {
+	*pageCache
+}
+
+const keyPageSize = 4096
+const keyCachePages = 256
+
+type pageCache struct {
+	reader   io.ReaderAt
+	size     int64
+	pageSize int64
+	maxPages int
+
+	mu    sync.Mutex
+	pages map[int64]*list.Element
+	order *list.List
+}
+
+type cachedPage struct {
+	number int64
+	data   []byte
+}
+
+func newPageCache(reader io.ReaderAt, size int64, pageSize int, maxPages int) *pageCache {
+	return &pageCache{
+		reader:   reader,
+		size:     size,
+		pageSize: int64(pageSize),
+		maxPages: maxPages,
+		pages:    map[int64]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+func (c *pageCache) length() int64 {
+	return c.size
+}
+
+func (c *pageCache) byteAt(loc int64) (byte, error) {
+	if loc < 0 || loc >= c.size {
+		return 0, errorDecodeGeneric
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	page, err := c.getPage(loc / c.pageSize)
+	if err != nil {
+		return 0, err
+	}
+	return page[loc%c.pageSize], nil
+}
+
+// getPage returns a page from the cache, reading it and evicting
+// the least recently used page if needed. The caller must hold c.mu.
+func (c *pageCache) getPage(number int64) ([]byte, error) {
+	if element, ok := c.pages[number]; ok {
+		c.order.MoveToFront(element)
+		return element.Value.(*cachedPage).data, nil
+	}
+
+	start := number * c.pageSize
+	size := c.pageSize
+	if start+size > c.size {
+		size = c.size - start
+	}
+
+	var data []byte
+	if c.order.Len() >= c.maxPages {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		evicted := oldest.Value.(*cachedPage)
+		delete(c.pages, evicted.number)
+		data = evicted.data[:size]
+	} else {
+		data = make([]byte, size, c.pageSize)
+	}
+
+	n, err := c.reader.ReadAt(data, start)
+	if n < len(data) {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	c.pages[number] = c.order.PushFront(&cachedPage{number: number, data: data})
+	return data, nil
+}
+
+func (k readerAtKey) Version() EncoderInfo {
+	return bytesKey(nil).Version()
+}
+
+func (k readerAtKey) CheckValid() (bool, error) {
+	if k.pageCache == nil || k.size < minByteKeySize {
+		return false, errorByteKeyTooShort
+	}
+	return true, nil
+}
+
+func (k readerAtKey) DictionarySet() string {
+	return bytesKey(nil).DictionarySet()
+}
+
+func (k readerAtKey) WriteMaterial(w io.Writer) error {
+	_, err := io.Copy(w, io.NewSectionReader(k.reader, 0, k.size))
+	return err
+}
+
+// NewReaderAtKey returns a bytes Key for use with Encode and Decode
+// which reads size bytes from key as they are needed.
+// At most 256 pages of 4096 bytes from the key are kept in memory.
+// Fingerprinting a message with the key reads the whole key.
+func NewReaderAtKey(key io.ReaderAt, size int64) Key {
+	return readerAtKey{newPageCache(key, size, keyPageSize, keyCachePages)}
+}
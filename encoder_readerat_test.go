@@ -0,0 +1,151 @@
+package decouplet
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+type countingReaderAt struct {
+	reader *bytes.Reader
+	reads  int
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	c.reads++
+	return c.reader.ReadAt(p, off)
+}
+
+func TestReaderAtMessage(t *testing.T) {
+	key := make([]byte, 1<<20)
+	_, err := rand.Read(key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	originalMessage :=
+		"!!**_-+Test THIS bigger message with More Symbols" +
+			"@$_()#$%^#@!~#2364###$%! *(#$%)^@#%$@"
+	readerKey := NewReaderAtKey(bytes.NewReader(key), int64(len(key)))
+	newMessage, err := Encode([]byte(originalMessage), readerKey)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	message, err := DecodeBytes(newMessage, key)
+	if err != nil {
+		t.Error(err)
+	}
+	if originalMessage != string(message) {
+		t.Log("bytes key did not decode reader key message")
+		t.Fail()
+	}
+	newMessage, err = EncodeBytes([]byte(originalMessage), key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	message, err = Decode(newMessage, readerKey)
+	if err != nil {
+		t.Error(err)
+	}
+	if originalMessage != string(message) {
+		t.Log("reader key did not decode bytes key message")
+		t.Fail()
+	}
+}
+
+func TestReaderAtPageCache(t *testing.T) {
+	key := make([]byte, 64*keyPageSize)
+	_, err := rand.Read(key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	reader := &countingReaderAt{reader: bytes.NewReader(key)}
+	cache := newPageCache(reader, int64(len(key)), keyPageSize, 4)
+	for i := int64(len(key)) - 1; i >= 0; i -= 1000 {
+		b, err := cache.byteAt(i)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if b != key[i] {
+			t.Error("wrong byte at", i)
+		}
+		if cache.order.Len() > 4 || len(cache.pages) > 4 {
+			t.Error("cache grew beyond its bound:", cache.order.Len())
+		}
+	}
+	reads := reader.reads
+	for i := 0; i < 100; i++ {
+		cache.byteAt(0)
+	}
+	if reader.reads != reads {
+		t.Error("cached page was read again")
+	}
+	_, err = cache.byteAt(int64(len(key)))
+	if err == nil {
+		t.Error("expected error reading past end of key")
+	}
+}
+
+func TestMapFileMessage(t *testing.T) {
+	key := make([]byte, 1<<16)
+	_, err := rand.Read(key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	file, err := ioutil.TempFile("", "decouplet-key")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	_, err = file.Write(key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	mapped, err := MapFile(file)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer mapped.Close()
+	if mapped.Size() != int64(len(key)) {
+		t.Error("unexpected mapped size:", mapped.Size())
+	}
+	msg := []byte("Test this message against a mapped file")
+	newMessage, err := Encode(msg, mapped.Key())
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	message, err := DecodeBytes(newMessage, key)
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(msg, message) {
+		t.Log("bytes are not equal")
+		t.Fail()
+	}
+}
+
+func TestMapFileEmpty(t *testing.T) {
+	file, err := ioutil.TempFile("", "decouplet-key")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	_, err = MapFile(file)
+	if err != errorMapSize {
+		t.Error("expected error mapping an empty file:", err)
+	}
+}
@@ -0,0 +1,168 @@
+package decouplet
+
+import (
+	"io"
+	"math/rand"
+	"unicode/utf8"
+)
+
+func init() {
+	RegisterCodec(textKey(nil).Version().Name, Codec{
+		Groups: 2,
+		Encode: findTextPattern,
+		Decode: getTextDefs,
+	})
+}
+
+// textKey holds one value for each rune of a text corpus,
+// so that locations are rune positions rather than byte offsets.
+type textKey []byte
+
+const minTextKeySize = 64
+
+var errorTextKeyTooShort error = keyError("key is smaller than minimum length of 64 runes")
+var errorTextKeyInvalid error = keyError("key is not valid UTF-8 text")
+
+func newTextKey(text string) (textKey, error) {
+	if !utf8.ValidString(text) {
+		return nil, errorTextKeyInvalid
+	}
+	values := make(textKey, 0, len(text))
+	for _, r := range text {
+		values = append(values, runeValue(r))
+	}
+	return values, nil
+}
+
+// runeValue folds every byte of a rune into a single value,
+// so that multi-byte runes still vary in the dictionary.
+func runeValue(r rune) byte {
+	return byte(r) ^ byte(r>>8) ^ byte(r>>16)
+}
+
+func (textKey) Version() EncoderInfo {
+	return EncoderInfo{
+		Name:    "txtec",
+		Version: "0.1",
+	}
+}
+
+func (k textKey) CheckValid() (bool, error) {
+	if len(k) < minTextKeySize {
+		return false, errorTextKeyTooShort
+	}
+	return true, nil
+}
+
+func (k textKey) checkVariance() int {
+	return bytesKey(k).checkVariance()
+}
+
+func (k textKey) length() int64 {
+	return int64(len(k))
+}
+
+func (k textKey) byteAt(loc int64) (byte, error) {
+	return k[loc], nil
+}
+
+func (k textKey) WriteMaterial(w io.Writer) error {
+	_, err := w.Write(k)
+	return err
+}
+
+func (textKey) DictionarySet() string {
+	return bytesKey(nil).DictionarySet()
+}
+
+func (textKey) getDictionary() dictionary {
+	return bytesKey(nil).getDictionary()
+}
+
+// NewTextKey returns a Key for use with Encode and Decode from UTF-8 text.
+func NewTextKey(key string) (Key, error) {
+	text, err := newTextKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return text, nil
+}
+
+// EncodeText encodes a slice of bytes against a key which is UTF-8 text.
+func EncodeText(input []byte, key string, opts ...Option) ([]byte, error) {
+	text, err := newTextKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return encode(
+		input, text, findTextPattern, opts...)
+}
+
+// EncodeTextStream encodes a byte stream against a key which is UTF-8 text.
+func EncodeTextStream(input io.Reader, key string, opts ...Option) (*io.PipeReader, error) {
+	text, err := newTextKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return encodeStream(
+		input, text, findTextPattern, opts...)
+}
+
+// EncodeTextStreamPartial encodes a byte stream partially against a key which is UTF-8 text.
+// Arguments take and skip are used to determine how many bytes to take, and skip along a stream.
+func EncodeTextStreamPartial(
+	input io.Reader, key string, take int, skip int, opts ...Option) (*io.PipeReader, error) {
+	text, err := newTextKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return encodePartialStream(
+		input, text, take, skip, findTextPattern, opts...)
+}
+
+// DecodeText decodes a slice of bytes against a key which is UTF-8 text.
+func DecodeText(input []byte, key string) ([]byte, error) {
+	text, err := newTextKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return decode(
+		input, text, 2, getTextDefs)
+}
+
+// DecodeTextStream decodes a byte stream against a key which is UTF-8 text.
+func DecodeTextStream(input io.Reader, key string) (*io.PipeReader, error) {
+	text, err := newTextKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return decodeStream(
+		input, text, 2, getTextDefs)
+}
+
+// DecodeTextStreamPartial decodes a byte stream with delimiters
+// against a key which is UTF-8 text.
+func DecodeTextStreamPartial(input io.Reader, key string) (*io.PipeReader, error) {
+	text, err := newTextKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return decodePartialStream(
+		input, text, 2, getTextDefs)
+}
+
+func getTextDefs(key Key, group DecodeGroup) (byte, error) {
+	text, ok := key.(textKey)
+	if !ok {
+		return 0, errorKeyCastFailed
+	}
+	return readByteDefs(text, text.getDictionary(), group)
+}
+
+func findTextPattern(char byte, key Key, random *rand.Rand) ([]byte, error) {
+	text, ok := key.(textKey)
+	if !ok {
+		return nil, errorKeyCastFailed
+	}
+	return matchBytePattern(char, text, text.getDictionary(), random)
+}
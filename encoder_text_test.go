@@ -0,0 +1,95 @@
+package decouplet
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+const testCorpus = "It was the best of times, it was the worst of times, " +
+	"it was the age of wisdom, it was the age of foolishness, " +
+	"it was the epoch of belief, it was the epoch of incredulity. " +
+	"Ça ne fait rien — 東京の空は青い. Ünïcödé runes count once."
+
+func TestTextMessage(t *testing.T) {
+	originalMessage :=
+		"!!**_-+Test THIS bigger message with More Symbols" +
+			"@$_()#$%^#@!~#2364###$%! *(#$%)^@#%$@"
+	newMessage, err := EncodeText([]byte(originalMessage), testCorpus)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	t.Log(string(newMessage))
+	message, err := DecodeText(newMessage, testCorpus)
+	if err != nil {
+		t.Error(err)
+	}
+	if originalMessage != string(message) {
+		t.Fail()
+	}
+}
+
+func TestTextRunePositions(t *testing.T) {
+	key, err := newTextKey(testCorpus)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(key) != len([]rune(testCorpus)) {
+		t.Error("key length is not counted in runes:", len(key))
+	}
+	newMessage, err := EncodeText([]byte("Test"), testCorpus)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	groups, err := findDecodeGroups(
+		bytes.TrimPrefix(newMessage, []byte("[dcplt-txtec-0.1]")),
+		dictionarySet(key.DictionarySet()), 2)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for _, g := range groups {
+		for _, p := range g.Place {
+			if len(p) > 3 {
+				t.Error("location outside rune range:", p)
+			}
+		}
+	}
+}
+
+func TestTextKeyInvalid(t *testing.T) {
+	_, err := EncodeText([]byte("Test"), "too short")
+	if err != errorTextKeyTooShort {
+		t.Error("expected key too short error, got:", err)
+	}
+	_, err = EncodeText([]byte("Test"), strings.Repeat("\xff", 100))
+	if err != errorTextKeyInvalid {
+		t.Error("expected invalid text error, got:", err)
+	}
+}
+
+func TestEncodeTextConcurrentPartial(t *testing.T) {
+	msg := []byte("Test this message and see it stream and be partially encoded! here")
+	take := 2
+	skip := 3
+	reader, err := EncodeTextStreamPartial(bytes.NewReader(msg), testCorpus, take, skip)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	newReader, err := DecodeTextStreamPartial(reader, testCorpus)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	b, err := ioutil.ReadAll(newReader)
+	t.Log(string(b))
+	if !bytes.Equal(msg, b) {
+		t.Log("bytes are not equal")
+		t.Fail()
+	}
+}
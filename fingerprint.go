@@ -0,0 +1,89 @@
+package decouplet
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"io"
+)
+
+const fingerprintParam = "fp="
+const fingerprintSaltSize = 8
+const fingerprintTagSize = 8
+
+var errorKeyMaterial error = keyError("key does not provide key material")
+
+// MaterialKey is implemented by keys which can write out their key material.
+// It is needed to fingerprint a key.
+type MaterialKey interface {
+	Key
+	// WriteMaterial writes the contents of the key which determine
+	// how messages are encoded.
+	WriteMaterial(w io.Writer) error
+}
+
+// keyDigest holds a key and, once it is first needed, a SHA-256 digest
+// of its key material, so that the material is read only once
+// for however many messages are encoded or decoded with it.
+type keyDigest struct {
+	key    Key
+	digest []byte
+}
+
+func newKeyDigest(key Key) *keyDigest {
+	return &keyDigest{key: key}
+}
+
+// sum returns the digest of the key material.
+func (k *keyDigest) sum() ([]byte, error) {
+	if k.digest != nil {
+		return k.digest, nil
+	}
+	material, ok := k.key.(MaterialKey)
+	if !ok {
+		return nil, errorKeyMaterial
+	}
+	digest := sha256.New()
+	err := material.WriteMaterial(digest)
+	if err != nil {
+		return nil, err
+	}
+	k.digest = digest.Sum(nil)
+	return k.digest, nil
+}
+
+// newFingerprint returns a random salt followed by
+// a tag of the salt keyed with the key digest.
+func newFingerprint(key *keyDigest, random random) ([]byte, error) {
+	salt := make([]byte, fingerprintSaltSize)
+	random.Read(salt)
+	if err := random.err(); err != nil {
+		return nil, err
+	}
+	digest, err := key.sum()
+	if err != nil {
+		return nil, err
+	}
+	return append(salt, fingerprintTag(digest, salt)...), nil
+}
+
+func checkFingerprint(key *keyDigest, fingerprint []byte) error {
+	if len(fingerprint) != fingerprintSaltSize+fingerprintTagSize {
+		return errorHeaderParam
+	}
+	digest, err := key.sum()
+	if err != nil {
+		return err
+	}
+	salt := fingerprint[:fingerprintSaltSize]
+	if !hmac.Equal(fingerprint[fingerprintSaltSize:], fingerprintTag(digest, salt)) {
+		return ErrWrongKey
+	}
+	return nil
+}
+
+func fingerprintTag(digest []byte, salt []byte) []byte {
+	mac := hmac.New(sha256.New, digest)
+	mac.Write([]byte("decouplet fingerprint"))
+	mac.Write(salt)
+	return mac.Sum(nil)[:fingerprintTagSize]
+}
@@ -0,0 +1,154 @@
+package decouplet
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// countingKey counts how many times its key material is written.
+type countingKey struct {
+	bytesKey
+	writes *int
+}
+
+func (k countingKey) WriteMaterial(w io.Writer) error {
+	*k.writes++
+	return k.bytesKey.WriteMaterial(w)
+}
+
+func TestFingerprintWrongKey(t *testing.T) {
+	key := make([]byte, 256)
+	_, err := rand.Read(key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	key2 := make([]byte, 256)
+	_, err = rand.Read(key2)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	msg := []byte("Test this fingerprinted message")
+	newMessage, err := EncodeBytes(msg, key, WithFingerprint())
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	t.Log(string(newMessage))
+	message, err := DecodeBytes(newMessage, key)
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(msg, message) {
+		t.Log("bytes are not equal")
+		t.Fail()
+	}
+	_, err = DecodeBytes(newMessage, key2)
+	if err != ErrWrongKey {
+		t.Error("expected wrong key error, got:", err)
+	}
+}
+
+func TestFingerprintSalted(t *testing.T) {
+	key := make([]byte, 256)
+	_, err := rand.Read(key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	first, err := newFingerprint(newKeyDigest(bytesKey(key)), options{}.newRandom())
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	second, err := newFingerprint(newKeyDigest(bytesKey(key)), options{}.newRandom())
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if bytes.Equal(first, second) {
+		t.Error("fingerprints of the same key should differ")
+	}
+	if bytes.Contains(first, key[:fingerprintTagSize]) {
+		t.Error("fingerprint contains key bytes")
+	}
+}
+
+func TestKeyDigestOnce(t *testing.T) {
+	key := make([]byte, 256)
+	_, err := rand.Read(key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	writes := 0
+	digest := newKeyDigest(countingKey{bytesKey: key, writes: &writes})
+	fingerprint, err := newFingerprint(digest, options{}.newRandom())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = checkFingerprint(digest, fingerprint)
+	if err != nil {
+		t.Error(err)
+	}
+	if writes != 1 {
+		t.Error("key material was read more than once:", writes)
+	}
+}
+
+func TestFingerprintStream(t *testing.T) {
+	image, err := LoadImage("images/test.png")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	image2, err := LoadImage("images/test.jpg")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	msg := []byte("Test this message and see it stream")
+	reader, err := EncodeImageStream(bytes.NewReader(msg), image, WithFingerprint())
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	encoded, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	newReader, err := DecodeImageStream(bytes.NewReader(encoded), image2)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	_, err = ioutil.ReadAll(newReader)
+	if err != ErrWrongKey {
+		t.Error("expected wrong key error, got:", err)
+	}
+	newReader, err = DecodeImageStream(bytes.NewReader(encoded), image)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	b, err := ioutil.ReadAll(newReader)
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(msg, b) {
+		t.Log("bytes are not equal")
+		t.Fail()
+	}
+}
+
+func TestFingerprintNoMaterial(t *testing.T) {
+	_, err := Encode([]byte("Test"), newIndexKey(), WithFingerprint())
+	if err != errorKeyMaterial {
+		t.Error("expected key material error, got:", err)
+	}
+}
@@ -0,0 +1,161 @@
+package decouplet
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math/bits"
+	"strconv"
+)
+
+// Format is the format encoded locations are written in.
+type Format int
+
+const (
+	// FormatText writes each location as a dictionary character
+	// followed by its position in decimal, such as "c1234f98".
+	FormatText Format = iota
+	// FormatBinary writes each location as a varint of its position,
+	// with the dictionary character packed into the lowest bits.
+	// It requires a codec whose locations are decimal positions.
+	FormatBinary
+)
+
+const binaryFormatParam = "bin"
+
+// kindBits returns the number of bits needed to
+// hold any character of a dictionary set.
+func kindBits(set dictionarySet) uint {
+	return uint(bits.Len(uint(len(set) - 1)))
+}
+
+// packLocations appends the locations of an encoded byte,
+// written in FormatText, to out in FormatBinary.
+func packLocations(out []byte, text []byte, set dictionarySet) ([]byte, error) {
+	shift := kindBits(set)
+	buffer := make([]byte, binary.MaxVarintLen64)
+	for i := 0; i < len(text); {
+		kind := -1
+		for k := range set {
+			if set[k] == text[i] {
+				kind = k
+			}
+		}
+		if kind < 0 {
+			return nil, errorDecodeNotFound
+		}
+		end := i + 1
+		for end < len(text) && !set.checkIn(text[end]) {
+			end++
+		}
+		loc, err := strconv.ParseUint(string(text[i+1:end]), 10, 64-int(shift))
+		if err != nil {
+			return nil, err
+		}
+		n := binary.PutUvarint(buffer, loc<<shift|uint64(kind))
+		out = append(out, buffer[:n]...)
+		i = end
+	}
+	return out, nil
+}
+
+// readBinaryGroup reads the locations of a single byte written in FormatBinary.
+// It returns io.EOF only when the reader ends before the group starts.
+func readBinaryGroup(reader io.ByteReader, set dictionarySet, groups int) (DecodeGroup, error) {
+	shift := kindBits(set)
+	group := DecodeGroup{
+		Kind:  make([]uint8, 0, groups),
+		Place: make([]string, 0, groups),
+	}
+	for i := 0; i < groups; i++ {
+		value, err := binary.ReadUvarint(reader)
+		if err == io.EOF && i > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return group, err
+		}
+		kind := int(value & (1<<shift - 1))
+		if kind >= len(set) {
+			return group, errorDecodeNotFound
+		}
+		group.Kind = append(group.Kind, set[kind])
+		group.Place = append(group.Place, strconv.FormatUint(value>>shift, 10))
+	}
+	return group, nil
+}
+
+// readTextGroup reads the locations of a single byte written in FormatText.
+// It returns io.EOF only when the reader ends before the group starts.
+func readTextGroup(reader io.ByteScanner, set dictionarySet, groups int) (DecodeGroup, error) {
+	group := DecodeGroup{
+		Kind:  make([]uint8, 0, groups),
+		Place: make([]string, 0, groups),
+	}
+	for i := 0; i < groups; i++ {
+		kind, err := reader.ReadByte()
+		if err == io.EOF && i > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return group, err
+		}
+		if !set.checkIn(kind) {
+			return group, errorDecodeNotFound
+		}
+		place := make([]byte, 0, 8)
+		for {
+			b, err := reader.ReadByte()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return group, err
+			}
+			if set.checkIn(b) {
+				err = reader.UnreadByte()
+				if err != nil {
+					return group, err
+				}
+				break
+			}
+			place = append(place, b)
+		}
+		if len(place) == 0 {
+			return group, errorDecodeGroup
+		}
+		group.Kind = append(group.Kind, kind)
+		group.Place = append(group.Place, string(place))
+	}
+	return group, nil
+}
+
+func decodeBinary(
+	reader *bufio.Reader,
+	key Key,
+	groups int,
+	decodeFunc func(Key, DecodeGroup) (byte, error),
+	writer io.Writer,
+) error {
+	set := dictionarySet(key.DictionarySet())
+	output := bufio.NewWriter(writer)
+	for {
+		group, err := readBinaryGroup(reader, set, groups)
+		if err == io.EOF {
+			return output.Flush()
+		}
+		if err != nil {
+			output.Flush()
+			return err
+		}
+		b, err := decodeFunc(key, group)
+		if err != nil {
+			output.Flush()
+			return err
+		}
+		err = output.WriteByte(b)
+		if err != nil {
+			return err
+		}
+	}
+}
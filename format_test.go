@@ -0,0 +1,131 @@
+package decouplet
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+	"testing"
+)
+
+func TestBinaryByteMessage(t *testing.T) {
+	key := make([]byte, 256)
+	_, err := rand.Read(key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	originalMessage :=
+		"!!**_-+Test THIS bigger message with More Symbols" +
+			"@$_()#$%^#@!~#2364###$%! *(#$%)^@#%$@"
+	textMessage, err := EncodeBytes([]byte(originalMessage), key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	newMessage, err := EncodeBytes(
+		[]byte(originalMessage), key, WithFormat(FormatBinary))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !bytes.HasPrefix(newMessage, []byte("[dcplt-byteec-0.2;bin]")) {
+		t.Error("missing binary header:", string(newMessage[:24]))
+	}
+	t.Log("Length of text:", len(textMessage), "binary:", len(newMessage))
+	if len(newMessage) >= len(textMessage) {
+		t.Error("binary message is not smaller than text")
+	}
+	message, err := DecodeBytes(newMessage, key)
+	if err != nil {
+		t.Error(err)
+	}
+	if originalMessage != string(message) {
+		t.Fail()
+	}
+}
+
+func TestBinaryImageMessage(t *testing.T) {
+	image, err := LoadImage("images/test.jpg")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	msg := []byte("Test this message in binary against an image")
+	newMessage, err := EncodeImage(msg, image, WithFormat(FormatBinary))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	message, err := DecodeImage(newMessage, image)
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(msg, message) {
+		t.Log("bytes are not equal")
+		t.Fail()
+	}
+}
+
+func TestBinaryStream(t *testing.T) {
+	key := make([]byte, 256)
+	_, err := rand.Read(key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	msg := []byte("Test this message and see it stream in binary")
+	reader, err := EncodeBytesStream(bytes.NewReader(msg), key, WithFormat(FormatBinary))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	newReader, err := DecodeBytesStream(reader, key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	b, err := ioutil.ReadAll(newReader)
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(msg, b) {
+		t.Log("bytes are not equal")
+		t.Fail()
+	}
+}
+
+func TestBinaryTruncated(t *testing.T) {
+	key := make([]byte, 256)
+	_, err := rand.Read(key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	newMessage, err := EncodeBytes([]byte("Test"), key, WithFormat(FormatBinary))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	_, err = DecodeBytes(newMessage[:len(newMessage)-1], key)
+	if err == nil {
+		t.Error("expected error decoding truncated message")
+	}
+}
+
+func TestPackLocations(t *testing.T) {
+	set := dictionarySet("abcdefghijk")
+	packed, err := packLocations(nil, []byte("c1234f98"), set)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	group, err := readBinaryGroup(bytes.NewReader(packed), set, 2)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if string(group.Kind) != "cf" ||
+		group.Place[0] != "1234" || group.Place[1] != "98" {
+		t.Error("unexpected group:", string(group.Kind), group.Place)
+	}
+}
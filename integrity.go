@@ -0,0 +1,95 @@
+package decouplet
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+)
+
+const integrityParam = "mac"
+const integrityTrailerStart = "[mac:"
+const integrityTrailerSize = len(integrityTrailerStart) + sha256.Size*2 + 1
+
+// ErrIntegrity is returned when an encoded message does not match
+// the integrity tag written with it, because it was corrupted,
+// altered, or decoded with a different key.
+var ErrIntegrity = errors.New("message failed integrity check")
+
+// newIntegrityMAC returns an HMAC keyed from the key material,
+// which covers the header and encoded body of a message.
+func newIntegrityMAC(key *keyDigest) (hash.Hash, error) {
+	digest, err := key.sum()
+	if err != nil {
+		return nil, err
+	}
+	derive := hmac.New(sha256.New, digest)
+	derive.Write([]byte("decouplet integrity"))
+	return hmac.New(sha256.New, derive.Sum(nil)), nil
+}
+
+func integrityTrailer(mac hash.Hash) []byte {
+	return []byte(integrityTrailerStart + hex.EncodeToString(mac.Sum(nil)) + "]")
+}
+
+// checkIntegrity verifies the trailer at the end of a whole message,
+// and returns the body with the trailer removed.
+func checkIntegrity(key *keyDigest, message []byte, body []byte) ([]byte, error) {
+	if len(body) < integrityTrailerSize {
+		return nil, ErrIntegrity
+	}
+	mac, err := newIntegrityMAC(key)
+	if err != nil {
+		return nil, err
+	}
+	mac.Write(message[:len(message)-integrityTrailerSize])
+	if !hmac.Equal(message[len(message)-integrityTrailerSize:], integrityTrailer(mac)) {
+		return nil, ErrIntegrity
+	}
+	return body[:len(body)-integrityTrailerSize], nil
+}
+
+// macReader passes through a stream while holding back its trailer,
+// and verifies the trailer against everything read once the stream ends.
+type macReader struct {
+	reader io.Reader
+	mac    hash.Hash
+	buffer []byte
+	held   []byte
+	err    error
+}
+
+func newMACReader(reader io.Reader, mac hash.Hash) *macReader {
+	return &macReader{
+		reader: reader,
+		mac:    mac,
+		buffer: make([]byte, 4096),
+	}
+}
+
+func (m *macReader) Read(p []byte) (int, error) {
+	for len(m.held) <= integrityTrailerSize {
+		if m.err == io.EOF {
+			return 0, m.verify()
+		}
+		if m.err != nil {
+			return 0, m.err
+		}
+		n, err := m.reader.Read(m.buffer)
+		m.held = append(m.held, m.buffer[:n]...)
+		m.err = err
+	}
+	n := copy(p, m.held[:len(m.held)-integrityTrailerSize])
+	m.mac.Write(m.held[:n])
+	m.held = m.held[n:]
+	return n, nil
+}
+
+func (m *macReader) verify() error {
+	if !hmac.Equal(m.held, integrityTrailer(m.mac)) {
+		return ErrIntegrity
+	}
+	return io.EOF
+}
@@ -0,0 +1,118 @@
+package decouplet
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+	"testing"
+)
+
+func TestIntegrityMessage(t *testing.T) {
+	key := make([]byte, 256)
+	_, err := rand.Read(key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	msg := []byte("Test this message with an integrity tag")
+	newMessage, err := EncodeBytes(msg, key, WithIntegrity())
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	t.Log(string(newMessage))
+	message, err := DecodeBytes(newMessage, key)
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(msg, message) {
+		t.Log("bytes are not equal")
+		t.Fail()
+	}
+
+	tampered := append([]byte{}, newMessage...)
+	i := bytes.IndexAny(tampered[len("[dcplt-byteec-0.2;mac]"):], "0123456789") +
+		len("[dcplt-byteec-0.2;mac]")
+	tampered[i] = '0' + (tampered[i]-'0'+1)%10
+	message, err = DecodeBytes(tampered, key)
+	if err != ErrIntegrity {
+		t.Error("expected integrity error, got:", err)
+	}
+	if message != nil {
+		t.Error("tampered message returned bytes")
+	}
+	_, err = DecodeBytes(newMessage[:len(newMessage)-1], key)
+	if err != ErrIntegrity {
+		t.Error("expected integrity error for truncated message, got:", err)
+	}
+}
+
+func TestIntegrityStream(t *testing.T) {
+	key := make([]byte, 256)
+	_, err := rand.Read(key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	msg := []byte("Test this message and see it stream with integrity")
+	for _, format := range []Format{FormatText, FormatBinary} {
+		reader, err := EncodeBytesStream(
+			bytes.NewReader(msg), key, WithIntegrity(), WithFormat(format), WithFingerprint())
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		encoded, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		newReader, err := DecodeBytesStream(bytes.NewReader(encoded), key)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		b, err := ioutil.ReadAll(newReader)
+		if err != nil {
+			t.Error(err)
+		}
+		if !bytes.Equal(msg, b) {
+			t.Log("bytes are not equal")
+			t.Fail()
+		}
+
+		tampered := append([]byte{}, encoded...)
+		tampered[len(tampered)-2] ^= 1
+		newReader, err = DecodeBytesStream(bytes.NewReader(tampered), key)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		_, err = ioutil.ReadAll(newReader)
+		if err != ErrIntegrity {
+			t.Error("expected integrity error, got:", err)
+		}
+	}
+}
+
+func TestIntegrityKeyDigest(t *testing.T) {
+	key := make([]byte, 256)
+	_, err := rand.Read(key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	writes := 0
+	counted := countingKey{bytesKey: key, writes: &writes}
+	h, err := newHeader(counted, options{fingerprint: true, integrity: true}, options{}.newRandom())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = newIntegrityMAC(h.digest)
+	if err != nil {
+		t.Error(err)
+	}
+	if writes != 1 {
+		t.Error("key material was read more than once for a message:", writes)
+	}
+}
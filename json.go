@@ -0,0 +1,302 @@
+package decouplet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+const jsonPathRoot = "$"
+const jsonPathWildcard = "*"
+
+var errorJSONPath = errors.New("json path is not valid")
+var errorJSONEncoded = errors.New("json string outside the paths begins like an encoded value")
+var errorJSONDecoded = errors.New("decoded json value is not valid")
+
+// jsonStep is a single step of a path through a JSON document,
+// either a key of an object or an index of an array.
+type jsonStep struct {
+	key      string
+	index    int
+	array    bool
+	wildcard bool
+}
+
+// EncodeJSON encodes the values of a JSON document at paths against any key
+// with a registered Codec, leaving the rest of the document as it is.
+// Each value, as written in the document, is replaced by a string holding
+// its encoded text. Paths start at "$", followed by ".name" or "['name']"
+// for a key of an object, "[n]" for an index of an array, and ".*" or "[*]"
+// to select every key or index, so "$.cards[*].number" selects the number
+// of every card. A document may hold several values one after another.
+// No string outside the paths may begin with "[dcplt-", as strings
+// beginning with a header are decoded by DecodeJSON.
+func EncodeJSON(input []byte, key Key, paths []string, opts ...Option) ([]byte, error) {
+	codec, err := getCodec(key)
+	if err != nil {
+		return nil, err
+	}
+	return encodeJSON(input, key, paths, codec.Encode, opts...)
+}
+
+// DecodeJSON decodes the values of a JSON document encoded with EncodeJSON
+// against any key with a registered Codec, restoring the original document.
+func DecodeJSON(input []byte, key Key) ([]byte, error) {
+	codec, err := getCodec(key)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSON(input, key, codec.Groups, codec.Decode)
+}
+
+func encodeJSON(
+	input []byte,
+	key Key,
+	paths []string,
+	encoder func(byte, Key, *rand.Rand) ([]byte, error),
+	opts ...Option,
+) ([]byte, error) {
+	if valid, err := key.CheckValid(); !valid {
+		return nil, err
+	}
+	patterns := make([][]jsonStep, len(paths))
+	for i, path := range paths {
+		pattern, err := parseJSONPath(path)
+		if err != nil {
+			return nil, err
+		}
+		patterns[i] = pattern
+	}
+	selected := func(path []jsonStep) bool {
+		for _, pattern := range patterns {
+			if matchJSONPath(pattern, path) {
+				return true
+			}
+		}
+		return false
+	}
+	// Values share a key digest, so the key material is read once.
+	opts = append(append([]Option(nil), opts...),
+		WithFormat(FormatText), withKeyDigest(newKeyDigest(key)))
+	return rewriteJSON(input,
+		func(path []jsonStep, first byte) bool {
+			return first == '"' || selected(path)
+		},
+		func(path []jsonStep, raw []byte) ([]byte, error) {
+			if !selected(path) {
+				var s string
+				err := json.Unmarshal(raw, &s)
+				if err != nil {
+					return nil, err
+				}
+				if strings.HasPrefix(s, headerStart) {
+					return nil, errorJSONEncoded
+				}
+				return raw, nil
+			}
+			encoded, err := encode(raw, key, encoder, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(string(encoded))
+		})
+}
+
+func decodeJSON(
+	input []byte,
+	key Key,
+	groups int,
+	decodeFunc func(Key, DecodeGroup) (byte, error),
+) ([]byte, error) {
+	if valid, err := key.CheckValid(); !valid {
+		return nil, err
+	}
+	prefix := headerStart + key.Version().Name + "-"
+	digest := newKeyDigest(key)
+	return rewriteJSON(input,
+		func(path []jsonStep, first byte) bool {
+			return first == '"'
+		},
+		func(path []jsonStep, raw []byte) ([]byte, error) {
+			var s string
+			err := json.Unmarshal(raw, &s)
+			if err != nil {
+				return nil, err
+			}
+			if !strings.HasPrefix(s, prefix) {
+				return raw, nil
+			}
+			decoded, err := decodeDigest([]byte(s), key, digest, groups, decodeFunc)
+			if err != nil {
+				return nil, err
+			}
+			if !json.Valid(decoded) {
+				return nil, errorJSONDecoded
+			}
+			return decoded, nil
+		})
+}
+
+// rewriteJSON walks the values of a JSON document, replacing each value
+// for which replace returns true with the output of rewrite.
+// Replace is given the first byte of the value, and when it returns true
+// the values within it are not walked. The rest of the document is kept as it is.
+func rewriteJSON(
+	input []byte,
+	replace func(path []jsonStep, first byte) bool,
+	rewrite func(path []jsonStep, raw []byte) ([]byte, error),
+) ([]byte, error) {
+	w := &jsonWalker{
+		input:   input,
+		decoder: json.NewDecoder(bytes.NewReader(input)),
+		replace: replace,
+		rewrite: rewrite,
+		output:  &bytes.Buffer{},
+	}
+	for w.valueStart() < len(input) {
+		err := w.walk(nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	w.output.Write(input[w.written:])
+	return w.output.Bytes(), nil
+}
+
+type jsonWalker struct {
+	input   []byte
+	decoder *json.Decoder
+	replace func(path []jsonStep, first byte) bool
+	rewrite func(path []jsonStep, raw []byte) ([]byte, error)
+	output  *bytes.Buffer
+	written int
+}
+
+// valueStart returns the offset of the next value in the document,
+// skipping white space and separators.
+func (w *jsonWalker) valueStart() int {
+	i := int(w.decoder.InputOffset())
+	for i < len(w.input) && strings.IndexByte(" \t\r\n,:", w.input[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+func (w *jsonWalker) walk(path []jsonStep) error {
+	start := w.valueStart()
+	if start >= len(w.input) {
+		return io.ErrUnexpectedEOF
+	}
+	first := w.input[start]
+	if w.replace(path, first) {
+		var raw json.RawMessage
+		err := w.decoder.Decode(&raw)
+		if err != nil {
+			return err
+		}
+		end := int(w.decoder.InputOffset())
+		rewritten, err := w.rewrite(path, w.input[start:end])
+		if err != nil {
+			return err
+		}
+		w.output.Write(w.input[w.written:start])
+		w.output.Write(rewritten)
+		w.written = end
+		return nil
+	}
+	token, err := w.decoder.Token()
+	if err != nil {
+		return err
+	}
+	switch token {
+	case json.Delim('{'):
+		for w.decoder.More() {
+			name, err := w.decoder.Token()
+			if err != nil {
+				return err
+			}
+			err = w.walk(append(path, jsonStep{key: name.(string)}))
+			if err != nil {
+				return err
+			}
+		}
+		_, err = w.decoder.Token()
+	case json.Delim('['):
+		for i := 0; w.decoder.More(); i++ {
+			err = w.walk(append(path, jsonStep{index: i, array: true}))
+			if err != nil {
+				return err
+			}
+		}
+		_, err = w.decoder.Token()
+	}
+	return err
+}
+
+// parseJSONPath parses a path such as "$.cards[*].number" into its steps.
+func parseJSONPath(path string) ([]jsonStep, error) {
+	if !strings.HasPrefix(path, jsonPathRoot) {
+		return nil, errorJSONPath
+	}
+	path = path[len(jsonPathRoot):]
+	steps := make([]jsonStep, 0)
+	for len(path) > 0 {
+		switch path[0] {
+		case '.':
+			end := strings.IndexAny(path[1:], ".[") + 1
+			if end == 0 {
+				end = len(path)
+			}
+			name := path[1:end]
+			if name == "" {
+				return nil, errorJSONPath
+			}
+			steps = append(steps, jsonStep{key: name, wildcard: name == jsonPathWildcard})
+			path = path[end:]
+		case '[':
+			end := strings.IndexByte(path, ']')
+			if end < 0 {
+				return nil, errorJSONPath
+			}
+			inner := path[1:end]
+			if len(inner) >= 2 && inner[0] == '\'' && inner[len(inner)-1] == '\'' {
+				steps = append(steps, jsonStep{key: inner[1 : len(inner)-1]})
+			} else if inner == jsonPathWildcard {
+				steps = append(steps, jsonStep{array: true, wildcard: true})
+			} else {
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return nil, errorJSONPath
+				}
+				steps = append(steps, jsonStep{index: index, array: true})
+			}
+			path = path[end+1:]
+		default:
+			return nil, errorJSONPath
+		}
+	}
+	return steps, nil
+}
+
+// matchJSONPath reports whether the path to a value matches a parsed path.
+func matchJSONPath(pattern []jsonStep, path []jsonStep) bool {
+	if len(pattern) != len(path) {
+		return false
+	}
+	for i, step := range pattern {
+		if step.array != path[i].array {
+			return false
+		}
+		if step.wildcard {
+			continue
+		}
+		if step.key != path[i].key || step.index != path[i].index {
+			return false
+		}
+	}
+	return true
+}
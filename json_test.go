@@ -0,0 +1,134 @@
+package decouplet
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const jsonDocument = `{
+  "user": {"name": "alice", "ssn": "078-05-1120", "age": 31},
+  "cards": [
+    {"number": "4111111111111111", "expiry": "12/29"},
+    {"number": 5500000000000004, "expiry": "01/30"}
+  ],
+  "notes": ["a", {"secret": [1, 2.50, null]}],
+  "active": true
+}`
+
+func encodeJSONDocument(t *testing.T, document string, paths []string, opts ...Option) string {
+	encoded, err := EncodeBytesJSON([]byte(document), vectorBytesKey(), paths, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := json.NewDecoder(bytes.NewReader(encoded))
+	for values.More() {
+		var value interface{}
+		if err := values.Decode(&value); err != nil {
+			t.Fatal("encoded document is not valid json:", string(encoded))
+		}
+	}
+	decoded, err := DecodeBytesJSON(encoded, vectorBytesKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != document {
+		t.Error("decoded document is not equal:", string(decoded))
+	}
+	return string(encoded)
+}
+
+func TestJSONPaths(t *testing.T) {
+	encoded := encodeJSONDocument(t, jsonDocument,
+		[]string{"$.user.ssn", "$.cards[*].number", "$.notes[1]['secret']"},
+		WithFormat(FormatBinary))
+	for _, secret := range []string{"078-05-1120", "4111111111111111", "5500000000000004", "2.50"} {
+		if strings.Contains(encoded, secret) {
+			t.Error("secret left in encoded document:", secret)
+		}
+	}
+	for _, plain := range []string{`"name": "alice"`, `"expiry": "12/29"`, `"active": true`, `["a", {`} {
+		if !strings.Contains(encoded, plain) {
+			t.Error("document outside paths was changed:", plain)
+		}
+	}
+	var document struct {
+		Cards []struct {
+			Number string
+		}
+	}
+	err := json.Unmarshal([]byte(encoded), &document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, card := range document.Cards {
+		if !strings.HasPrefix(card.Number, headerStart) {
+			t.Error("value is not encoded as a string:", card.Number)
+		}
+	}
+}
+
+func TestJSONWholeDocuments(t *testing.T) {
+	encodeJSONDocument(t, jsonDocument, []string{"$"})
+	encodeJSONDocument(t, "{\"a\": 1}\n{\"a\": [2]}\n", []string{"$.a"})
+	encodeJSONDocument(t, jsonDocument, []string{"$.*"})
+	encodeJSONDocument(t, jsonDocument, []string{"$.missing", "$.cards[5]"})
+}
+
+func TestJSONErrors(t *testing.T) {
+	key := vectorBytesKey()
+	for _, path := range []string{"user", "$.", "$[x]", "$[-1]", "$[0", "$..a"} {
+		_, err := EncodeBytesJSON([]byte(jsonDocument), key, []string{path})
+		if err != errorJSONPath {
+			t.Error("expected path to be rejected:", path, err)
+		}
+	}
+	_, err := EncodeBytesJSON([]byte(`{"a": "[dcplt-x", "b": 1}`), key, []string{"$.b"})
+	if err != errorJSONEncoded {
+		t.Error("expected encoded string outside paths, got:", err)
+	}
+	_, err = EncodeBytesJSON([]byte(`{"a": [1, 2}`), key, []string{"$.b"})
+	if err == nil {
+		t.Error("expected malformed document to be rejected")
+	}
+}
+
+func TestParseJSONPath(t *testing.T) {
+	steps, err := parseJSONPath("$.cards[*].number['a.b'][2].*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []jsonStep{
+		{key: "cards"},
+		{array: true, wildcard: true},
+		{key: "number"},
+		{key: "a.b"},
+		{index: 2, array: true},
+		{key: "*", wildcard: true},
+	}
+	if !reflect.DeepEqual(steps, expected) {
+		t.Error("unexpected steps:", steps)
+	}
+}
+
+func TestJSONKeyDigest(t *testing.T) {
+	writes := 0
+	key := countingKey{bytesKey: vectorBytesKey(), writes: &writes}
+	encoded, err := encodeJSON([]byte(jsonDocument), key, []string{"$.cards[*].number"},
+		findBytePattern, WithFingerprint(), WithIntegrity())
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decodeJSON(encoded, key, 2, getByteDefs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != jsonDocument {
+		t.Error("decoded document is not equal:", string(decoded))
+	}
+	if writes != 2 {
+		t.Error("key material was not read once to encode and once to decode:", writes)
+	}
+}
@@ -1,39 +1,160 @@
 package decouplet
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"strings"
 )
 
-type encoderInfo struct {
+const headerStart = "[dcplt-"
+const headerEnd byte = ']'
+const headerParamSeparator = ";"
+
+// ErrEncoderVersion is returned when a message has no header, or was encoded
+// by a different encoder or version than the key decoding it.
+var ErrEncoderVersion = errors.New("encoder version does not match")
+var errorHeaderParam = errors.New("unknown header parameter")
+
+// ErrWrongKey is returned when a message carries a key fingerprint
+// which does not match the key it is being decoded with.
+var ErrWrongKey = errors.New("message was encoded with a different key")
+
+// EncoderInfo identifies the encoder and version used to produce a message.
+type EncoderInfo struct {
 	Name    string `json:"name"`
 	Version string `json:"version"`
 }
 
-func (i encoderInfo) getEncoderString() (string, error) {
-	return fmt.Sprintf(
-		"[dcplt-%s-%s]",
+// header holds the encoder information and the options
+// a message was encoded with.
+type header struct {
+	info        EncoderInfo
+	format      Format
+	forced      bool
+	fingerprint []byte
+	integrity   bool
+	raw         []byte
+	digest      *keyDigest
+}
+
+func newHeader(key Key, config options, random random) (header, error) {
+	h := header{
+		info:      key.Version(),
+		format:    config.format,
+		forced:    config.header,
+		integrity: config.integrity,
+		digest:    config.keyDigest(key),
+	}
+	if config.fingerprint {
+		fingerprint, err := newFingerprint(h.digest, random)
+		if err != nil {
+			return h, err
+		}
+		h.fingerprint = fingerprint
+	}
+	return h, nil
+}
+
+// written reports whether the header must be written before a stream.
+// Streams encoded as text with no other options have no header.
+func (h header) written() bool {
+	return h.forced || h.format != FormatText || h.fingerprint != nil || h.integrity
+}
+
+func (i EncoderInfo) getEncoderString(params ...string) (string, error) {
+	meta := fmt.Sprintf(
+		"%s%s-%s",
+		headerStart,
 		i.Name,
 		i.Version,
-	), nil
+	)
+	for _, p := range params {
+		meta += headerParamSeparator + p
+	}
+	return meta + string(headerEnd), nil
+}
+
+// checkEncoder removes the header from the start of a message, checking it
+// was written for the same encoder and, if fingerprinted, the same key.
+func checkEncoder(key *keyDigest, message *[]byte) (header, error) {
+	end := bytes.IndexByte(*message, headerEnd)
+	if end < 0 {
+		return header{}, ErrEncoderVersion
+	}
+	h, err := parseHeader(key, string((*message)[:end+1]))
+	if err != nil {
+		return h, err
+	}
+	*message = (*message)[end+1:]
+	return h, nil
 }
 
-func (i encoderInfo) checkEncoder(message *[]byte) error {
-	meta, err := i.getEncoderString()
+// readHeader reads a header from the start of a stream.
+// Streams encoded as text may have no header, in which case
+// the default header for the encoder is returned.
+func readHeader(key *keyDigest, reader *bufio.Reader) (header, error) {
+	start, err := reader.Peek(1)
+	if err != nil || start[0] != headerStart[0] {
+		return header{info: key.key.Version(), digest: key}, nil
+	}
+	meta, err := reader.ReadSlice(headerEnd)
 	if err != nil {
-		return err
+		return header{}, ErrEncoderVersion
 	}
-	metaBytes := []byte(meta)
-	if bytes.HasPrefix(*message, metaBytes) {
-		*message = (*message)[len(metaBytes):]
-		return nil
+	return parseHeader(key, string(meta))
+}
+
+func parseHeader(key *keyDigest, meta string) (header, error) {
+	i := key.key.Version()
+	if !strings.HasPrefix(meta, headerStart) {
+		return header{}, ErrEncoderVersion
 	}
-	return errors.New("encoder version does not match")
+	meta = strings.TrimSuffix(strings.TrimPrefix(meta, headerStart), string(headerEnd))
+	params := strings.Split(meta, headerParamSeparator)
+	if params[0] != i.Name+"-"+i.Version {
+		return header{}, ErrEncoderVersion
+	}
+	h := header{info: i, raw: []byte(headerStart + meta + string(headerEnd)), digest: key}
+	for _, p := range params[1:] {
+		switch {
+		case p == binaryFormatParam:
+			h.format = FormatBinary
+		case p == integrityParam:
+			h.integrity = true
+		case strings.HasPrefix(p, fingerprintParam):
+			fingerprint, err := hex.DecodeString(strings.TrimPrefix(p, fingerprintParam))
+			if err != nil {
+				return h, errorHeaderParam
+			}
+			h.fingerprint = fingerprint
+		default:
+			return h, errorHeaderParam
+		}
+	}
+	if h.fingerprint != nil {
+		err := checkFingerprint(key, h.fingerprint)
+		if err != nil {
+			return h, err
+		}
+	}
+	return h, nil
 }
 
-func (i encoderInfo) writeVersion() ([]byte, error) {
-	meta, err := i.getEncoderString()
+func (h header) writeVersion() ([]byte, error) {
+	params := make([]string, 0)
+	if h.format == FormatBinary {
+		params = append(params, binaryFormatParam)
+	}
+	if h.fingerprint != nil {
+		params = append(params, fingerprintParam+hex.EncodeToString(h.fingerprint))
+	}
+	if h.integrity {
+		params = append(params, integrityParam)
+	}
+	meta, err := h.info.getEncoderString(params...)
 	if err != nil {
 		return nil, err
 	}
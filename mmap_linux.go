@@ -0,0 +1,74 @@
+//go:build linux
+
+package decouplet
+
+import (
+	"errors"
+	"io"
+	"math"
+	"os"
+	"syscall"
+)
+
+var errorMapSize = errors.New("file is empty or too large to map")
+
+// MappedFile is a read-only file mapped into memory,
+// for use as a key larger than would be practical to load.
+type MappedFile struct {
+	data []byte
+}
+
+// MapFile maps an open file into memory as read-only.
+// The file may be closed once it has been mapped.
+// Empty files, and files larger than an int can address, are not mapped.
+func MapFile(f *os.File) (*MappedFile, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 || info.Size() > math.MaxInt {
+		return nil, errorMapSize
+	}
+	data, err := syscall.Mmap(
+		int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	return &MappedFile{data: data}, nil
+}
+
+// ReadAt reads bytes from the mapped file at an offset.
+func (m *MappedFile) ReadAt(p []byte, off int64) (int, error) {
+	return readMapped(m.data, p, off)
+}
+
+// Size returns the size of the mapped file in bytes.
+func (m *MappedFile) Size() int64 {
+	return int64(len(m.data))
+}
+
+// Key returns a bytes Key reading directly from the mapped file.
+func (m *MappedFile) Key() Key {
+	return bytesKey(m.data)
+}
+
+// Close unmaps the file. Keys from the file must not be used after Close.
+func (m *MappedFile) Close() error {
+	if m.data == nil {
+		return nil
+	}
+	data := m.data
+	m.data = nil
+	return syscall.Munmap(data)
+}
+
+func readMapped(data []byte, p []byte, off int64) (int, error) {
+	if off < 0 || off >= int64(len(data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
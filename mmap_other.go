@@ -0,0 +1,52 @@
+//go:build !linux
+
+package decouplet
+
+import (
+	"errors"
+	"os"
+)
+
+var errorMapSize = errors.New("file is empty or too large to map")
+
+// MappedFile is a read-only file used as a key larger than would be
+// practical to load. On this platform the file is read as needed
+// rather than mapped into memory.
+type MappedFile struct {
+	file *os.File
+	size int64
+}
+
+// MapFile prepares an open file for use as a key.
+// The file must stay open until the MappedFile is closed.
+// Empty files are not used.
+func MapFile(f *os.File) (*MappedFile, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 {
+		return nil, errorMapSize
+	}
+	return &MappedFile{file: f, size: info.Size()}, nil
+}
+
+// ReadAt reads bytes from the file at an offset.
+func (m *MappedFile) ReadAt(p []byte, off int64) (int, error) {
+	return m.file.ReadAt(p, off)
+}
+
+// Size returns the size of the file in bytes.
+func (m *MappedFile) Size() int64 {
+	return m.size
+}
+
+// Key returns a bytes Key reading from the file through a page cache.
+func (m *MappedFile) Key() Key {
+	return NewReaderAtKey(m.file, m.size)
+}
+
+// Close releases the file. The file itself is not closed.
+func (m *MappedFile) Close() error {
+	return nil
+}
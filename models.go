@@ -9,6 +9,22 @@ var errorDecodeNotFound = errors.New("valid decode character not found")
 var errorKeyCastFailed = errors.New("failed to cast key")
 var errorDecodeGeneric = errors.New("decode error")
 var errorDecodeGroup = errors.New("decode groups missing locations")
+var errorCodecNotFound = errors.New("no codec registered for encoder")
+
+// ErrInvalidKey is matched by errors.Is for every error
+// returned when a key cannot be used, such as one which is too small.
+var ErrInvalidKey = errors.New("key is not valid")
+
+// keyError is an error returned when a key cannot be used.
+type keyError string
+
+func (e keyError) Error() string {
+	return string(e)
+}
+
+func (e keyError) Is(target error) bool {
+	return target == ErrInvalidKey
+}
 
 const partialStart string = ";[&"
 const partialEnd string = "&];"
@@ -18,9 +34,11 @@ var partialEndBytes = []byte(partialEnd)
 
 type dictionarySet string
 
-type decodeGroup struct {
-	kind  []uint8
-	place []string
+// DecodeGroup holds the dictionary characters and locations
+// which together describe a single encoded byte.
+type DecodeGroup struct {
+	Kind  []uint8
+	Place []string
 }
 
 type dictionary struct {
@@ -32,11 +50,6 @@ type decodeRef struct {
 	amount    uint8
 }
 
-type splitInfo struct {
-	chars  dictionarySet
-	groups int
-}
-
 type location struct {
 	x int
 	y int
@@ -0,0 +1,117 @@
+package decouplet
+
+import (
+	"io"
+)
+
+// Option configures how a message is encoded.
+type Option func(*options)
+
+type options struct {
+	format       Format
+	header       bool
+	fingerprint  bool
+	integrity    bool
+	seeded       bool
+	seed         int64
+	randomReader io.Reader
+	workers      int
+	markers      *partialMarkers
+	digest       *keyDigest
+}
+
+func newOptions(opts []Option) options {
+	config := options{
+		format: FormatText,
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return config
+}
+
+// WithFormat sets the format encoded locations are written in.
+// Messages in FormatBinary are detected and decoded automatically.
+func WithFormat(format Format) Option {
+	return func(config *options) {
+		config.format = format
+	}
+}
+
+// WithHeader writes the message header before streams encoded as text,
+// which have none without other options. Decoding the stream against a key
+// for a different encoder then fails with ErrEncoderVersion.
+func WithHeader() Option {
+	return func(config *options) {
+		config.header = true
+	}
+}
+
+// WithFingerprint writes a fingerprint of the key into the message header.
+// Decoding the message with a different key then fails with ErrWrongKey
+// instead of returning the wrong bytes. The fingerprint is salted,
+// so it does not reveal the key or link messages encoded with it.
+func WithFingerprint() Option {
+	return func(config *options) {
+		config.fingerprint = true
+	}
+}
+
+// WithIntegrity appends an HMAC, keyed from the key material, to the message.
+// Decoding fails with ErrIntegrity if the message was corrupted or altered.
+// Whole messages are checked before any bytes are decoded,
+// while streams are checked when they end.
+func WithIntegrity() Option {
+	return func(config *options) {
+		config.integrity = true
+	}
+}
+
+// WithSeed chooses locations from a pseudo-random sequence with the given seed,
+// so encoding the same input against the same key gives identical output.
+// Seeded output is predictable, and is meant for tests and audits.
+func WithSeed(seed int64) Option {
+	return func(config *options) {
+		config.seeded = true
+		config.seed = seed
+		config.randomReader = nil
+	}
+}
+
+// WithRandom chooses locations using bytes read from r,
+// instead of the default of crypto/rand.
+// Encoding fails if r returns an error before encoding is done.
+func WithRandom(r io.Reader) Option {
+	return func(config *options) {
+		config.seeded = false
+		config.randomReader = r
+	}
+}
+
+// WithWorkers encodes the input in chunks on n goroutines,
+// writing the encoded chunks in their original order.
+// Seeded output is still reproducible for any n above one,
+// but is not the same as output encoded without workers.
+// With WithRandom, workers share the reader, so the order
+// its bytes are used in is not fixed.
+func WithWorkers(n int) Option {
+	return func(config *options) {
+		config.workers = n
+	}
+}
+
+// withKeyDigest shares a key digest between the messages of a stream,
+// such as the spans of a partial stream, so that it is computed once.
+func withKeyDigest(digest *keyDigest) Option {
+	return func(config *options) {
+		config.digest = digest
+	}
+}
+
+// keyDigest returns the shared key digest, or a new one for key.
+func (config options) keyDigest(key Key) *keyDigest {
+	if config.digest != nil {
+		return config.digest
+	}
+	return newKeyDigest(key)
+}
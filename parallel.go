@@ -0,0 +1,113 @@
+package decouplet
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+)
+
+// parallelChunkSize is the number of input bytes encoded by a worker at once.
+const parallelChunkSize = 256
+
+type encodeChunk struct {
+	data   []byte
+	random random
+	result chan encodeResult
+}
+
+type encodeResult struct {
+	encoded []byte
+	err     error
+}
+
+// chunkRandom returns the source of randomness for one chunk of a parallel encoding.
+// Chunks are seeded in order from r when a seed was given,
+// so output stays reproducible whatever the number of workers.
+// Otherwise each chunk reads from the reader given, or crypto/rand.
+func (config options) chunkRandom(r random) random {
+	if config.seeded {
+		return random{Rand: rand.New(rand.NewSource(r.Int63()))}
+	}
+	return config.newRandom()
+}
+
+// writeEncodedParallel reads the input in chunks, encodes them
+// on config.workers goroutines and writes them out in order.
+func writeEncodedParallel(
+	input io.Reader,
+	writer io.Writer,
+	key Key,
+	encoder func(byte, Key, *rand.Rand) ([]byte, error),
+	config options,
+	random random,
+) error {
+	if config.randomReader != nil {
+		config.randomReader = &lockedReader{reader: config.randomReader}
+	}
+	jobs := make(chan encodeChunk)
+	order := make(chan encodeChunk, config.workers)
+	done := make(chan struct{})
+	defer close(done)
+
+	var readErr error
+	go func() {
+		defer close(order)
+		defer close(jobs)
+		for {
+			data := make([]byte, parallelChunkSize)
+			n, err := io.ReadFull(input, data)
+			if n > 0 {
+				chunk := encodeChunk{
+					data:   data[:n],
+					random: config.chunkRandom(random),
+					result: make(chan encodeResult, 1),
+				}
+				if readErr = random.err(); readErr != nil {
+					return
+				}
+				select {
+				case order <- chunk:
+				case <-done:
+					return
+				}
+				select {
+				case jobs <- chunk:
+				case <-done:
+					return
+				}
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return
+			}
+			if err != nil {
+				readErr = err
+				return
+			}
+		}
+	}()
+
+	chunkConfig := config
+	chunkConfig.workers = 0
+	for i := 0; i < config.workers; i++ {
+		go func() {
+			for chunk := range jobs {
+				output := &bytes.Buffer{}
+				err := writeEncoded(
+					bytes.NewReader(chunk.data), output, key, encoder, chunkConfig, chunk.random)
+				chunk.result <- encodeResult{encoded: output.Bytes(), err: err}
+			}
+		}()
+	}
+
+	for chunk := range order {
+		result := <-chunk.result
+		if result.err != nil {
+			return result.err
+		}
+		_, err := writer.Write(result.encoded)
+		if err != nil {
+			return err
+		}
+	}
+	return readErr
+}
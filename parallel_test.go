@@ -0,0 +1,135 @@
+package decouplet
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+)
+
+func parallelMessage(size int) []byte {
+	msg := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(msg)
+	return msg
+}
+
+func TestParallelStream(t *testing.T) {
+	key := vectorBytesKey()
+	msg := parallelMessage(parallelChunkSize*10 + 17)
+	for _, format := range []Format{FormatText, FormatBinary} {
+		reader, err := EncodeBytesStream(bytes.NewReader(msg), key,
+			WithWorkers(4), WithFormat(format))
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeBytesStream(reader, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(decoded)
+		if err != nil {
+			t.Error(err)
+		}
+		if !bytes.Equal(msg, b) {
+			t.Error("bytes are not equal for format", format)
+		}
+	}
+}
+
+func TestParallelSeeded(t *testing.T) {
+	key := NewBytesKey(vectorBytesKey())
+	msg := parallelMessage(parallelChunkSize*3 + 1)
+	first, err := Encode(msg, key, WithSeed(11), WithWorkers(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Encode(msg, key, WithSeed(11), WithWorkers(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Error("seeded output depends on the number of workers")
+	}
+	decoded, err := Decode(first, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(msg, decoded) {
+		t.Error("bytes are not equal")
+	}
+}
+
+var errorTestRead = errors.New("test read error")
+
+type failingReader struct {
+	remaining int
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if f.remaining == 0 {
+		return 0, errorTestRead
+	}
+	if len(p) > f.remaining {
+		p = p[:f.remaining]
+	}
+	for i := range p {
+		p[i] = 'x'
+	}
+	f.remaining -= len(p)
+	return len(p), nil
+}
+
+func TestParallelReadError(t *testing.T) {
+	reader, err := EncodeBytesStream(
+		&failingReader{remaining: parallelChunkSize * 3}, vectorBytesKey(), WithWorkers(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.Copy(ioutil.Discard, reader)
+	if err != errorTestRead {
+		t.Error("expected read error, got:", err)
+	}
+}
+
+func TestParallelRandomReader(t *testing.T) {
+	msg := parallelMessage(parallelChunkSize * 4)
+	_, err := Encode(msg, NewBytesKey(vectorBytesKey()),
+		WithRandom(&failingReader{remaining: 64}), WithWorkers(4))
+	if err != errorTestRead {
+		t.Error("expected every chunk to read from the reader, got:", err)
+	}
+}
+
+func benchmarkEncodeStream(b *testing.B, key Key, size int, opts ...Option) {
+	msg := parallelMessage(size)
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reader, err := EncodeStream(bytes.NewReader(msg), key, opts...)
+		if err != nil {
+			b.Fatal(err)
+		}
+		_, err = io.Copy(ioutil.Discard, reader)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodeBytesStream(b *testing.B) {
+	benchmarkEncodeStream(b, NewBytesKey(vectorBytesKey()), 1<<16)
+}
+
+func BenchmarkEncodeBytesStreamParallel(b *testing.B) {
+	benchmarkEncodeStream(b, NewBytesKey(vectorBytesKey()), 1<<16, WithWorkers(4))
+}
+
+func BenchmarkEncodeImageStream(b *testing.B) {
+	benchmarkEncodeStream(b, NewImageKey(vectorImageKey()), 1<<10)
+}
+
+func BenchmarkEncodeImageStreamParallel(b *testing.B) {
+	benchmarkEncodeStream(b, NewImageKey(vectorImageKey()), 1<<10, WithWorkers(4))
+}
@@ -0,0 +1,480 @@
+package decouplet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// partialEscape comes before a byte of plain text in a partial stream
+// which is to be copied as it is, rather than read as the start of a span.
+const partialEscape byte = '\\'
+
+const partialStartParam = "start="
+const partialEndParam = "end="
+
+// partialEndExcluded holds the bytes which may be written within a span,
+// at least one byte of an end marker must not be one of them.
+const partialEndExcluded = "[]-.;=:"
+
+var errorPartialMarkers = errors.New("partial stream markers are not valid")
+var errorPartialSpan = errors.New("partial stream span is not closed")
+var errorPartialEscape = errors.New("partial stream ends with an escape")
+var errorPartialTake = errors.New("partial stream take must be above zero")
+var errorPartialSkip = errors.New("partial stream skip may not be negative")
+
+// partialInfo identifies partial streams which begin with a preamble
+// naming their markers, and escape their plain text. Streams without
+// a preamble use the markers in models.go, with no escaping.
+var partialInfo = EncoderInfo{
+	Name:    "partial",
+	Version: "0.2",
+}
+
+// partialMarkers are the markers around encoded spans in a partial stream.
+type partialMarkers struct {
+	start []byte
+	end   []byte
+}
+
+// WithPartialMarkers sets the markers written around encoded spans
+// by partial stream encoders. Neither may begin with a backslash,
+// and end must hold a byte other than a letter, a digit or one of "[]-.;=:",
+// so that it is never written within a span.
+func WithPartialMarkers(start string, end string) Option {
+	return func(config *options) {
+		config.markers = &partialMarkers{start: []byte(start), end: []byte(end)}
+	}
+}
+
+// partialMarkers returns the markers for a partial stream, checking they are valid.
+func (config options) partialMarkers() (partialMarkers, error) {
+	if config.markers == nil {
+		return partialMarkers{start: partialStartBytes, end: partialEndBytes}, nil
+	}
+	m := *config.markers
+	if len(m.start) == 0 || len(m.end) == 0 ||
+		m.start[0] == partialEscape || m.end[0] == partialEscape {
+		return m, errorPartialMarkers
+	}
+	for _, b := range m.end {
+		if !isAlphanumeric(b) && strings.IndexByte(partialEndExcluded, b) < 0 {
+			return m, nil
+		}
+	}
+	return m, errorPartialMarkers
+}
+
+func isAlphanumeric(b byte) bool {
+	return b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+func (m partialMarkers) preamble() ([]byte, error) {
+	meta, err := partialInfo.getEncoderString(
+		partialStartParam+hex.EncodeToString(m.start),
+		partialEndParam+hex.EncodeToString(m.end))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(meta), nil
+}
+
+// readPartialPreamble reads the markers of a partial stream from its preamble,
+// returning the size of the preamble, which is zero if it had none.
+func readPartialPreamble(reader *bufio.Reader) (partialMarkers, int, error) {
+	m := partialMarkers{}
+	start := headerStart + partialInfo.Name + "-"
+	peeked, err := reader.Peek(len(start))
+	if err != nil || string(peeked) != start {
+		return m, 0, nil
+	}
+	meta, err := reader.ReadSlice(headerEnd)
+	if err != nil {
+		return m, len(meta), ErrEncoderVersion
+	}
+	params := strings.Split(
+		strings.TrimSuffix(string(meta[len(headerStart):]), string(headerEnd)),
+		headerParamSeparator)
+	if params[0] != partialInfo.Name+"-"+partialInfo.Version {
+		return m, len(meta), ErrEncoderVersion
+	}
+	for _, p := range params[1:] {
+		switch {
+		case strings.HasPrefix(p, partialStartParam):
+			m.start, err = hex.DecodeString(strings.TrimPrefix(p, partialStartParam))
+		case strings.HasPrefix(p, partialEndParam):
+			m.end, err = hex.DecodeString(strings.TrimPrefix(p, partialEndParam))
+		default:
+			err = errorHeaderParam
+		}
+		if err != nil {
+			return m, len(meta), errorHeaderParam
+		}
+	}
+	if len(m.start) == 0 || len(m.end) == 0 {
+		return m, len(meta), errorPartialMarkers
+	}
+	return m, len(meta), nil
+}
+
+// partialWriter writes a partial stream, escaping plain text
+// so that it can hold the markers and any other bytes.
+type partialWriter struct {
+	writer  io.Writer
+	markers partialMarkers
+	key     Key
+	encoder func(byte, Key, *rand.Rand) ([]byte, error)
+	opts    []Option
+	// pending holds the end of the plain text written so far,
+	// which may be the beginning of a start marker.
+	pending []byte
+}
+
+func newPartialWriter(
+	writer io.Writer,
+	key Key,
+	encoder func(byte, Key, *rand.Rand) ([]byte, error),
+	opts []Option,
+) (*partialWriter, error) {
+	markers, err := newOptions(opts).partialMarkers()
+	if err != nil {
+		return nil, err
+	}
+	// Spans share a key digest, so the key material is read once.
+	opts = append(append([]Option(nil), opts...),
+		WithFormat(FormatText), withKeyDigest(newKeyDigest(key)))
+	return &partialWriter{
+		writer:  writer,
+		markers: markers,
+		key:     key,
+		encoder: encoder,
+		opts:    opts,
+	}, nil
+}
+
+func (p *partialWriter) writePreamble() error {
+	preamble, err := p.markers.preamble()
+	if err != nil {
+		return err
+	}
+	_, err = p.writer.Write(preamble)
+	return err
+}
+
+// writePlain writes plain text, escaping the escape byte and start markers.
+func (p *partialWriter) writePlain(data []byte) error {
+	data = append(p.pending, data...)
+	p.pending = nil
+	escaped := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		rest := data[i:]
+		if len(rest) < len(p.markers.start) && bytes.HasPrefix(p.markers.start, rest) {
+			p.pending = append(p.pending, rest...)
+			break
+		}
+		if data[i] == partialEscape || bytes.HasPrefix(rest, p.markers.start) {
+			escaped = append(escaped, partialEscape)
+		}
+		escaped = append(escaped, data[i])
+	}
+	_, err := p.writer.Write(escaped)
+	return err
+}
+
+// flush writes any plain text held back, escaping every byte
+// which could begin a start marker with the bytes written after it.
+func (p *partialWriter) flush() error {
+	escaped := make([]byte, 0, len(p.pending)*2)
+	for _, b := range p.pending {
+		if b == partialEscape || b == p.markers.start[0] {
+			escaped = append(escaped, partialEscape)
+		}
+		escaped = append(escaped, b)
+	}
+	p.pending = nil
+	_, err := p.writer.Write(escaped)
+	return err
+}
+
+// writeSpan writes data encoded between markers.
+func (p *partialWriter) writeSpan(data []byte) error {
+	err := p.flush()
+	if err != nil {
+		return err
+	}
+	_, err = p.writer.Write(p.markers.start)
+	if err != nil {
+		return err
+	}
+	e := newEncoder(p.writer, p.key, p.encoder, p.opts...)
+	_, err = e.Write(data)
+	if err == nil {
+		err = e.Close()
+	}
+	if err != nil {
+		return err
+	}
+	_, err = p.writer.Write(p.markers.end)
+	return err
+}
+
+// copyPartialSpans writes a partial stream with a preamble,
+// decoding its spans and removing the escaping from its plain text.
+// It stops at the first error, returning a *DecodeError positioned
+// within the whole stream if a span could not be decoded.
+func copyPartialSpans(
+	reader *bufio.Reader,
+	markers partialMarkers,
+	offset int64,
+	key Key,
+	groups int,
+	decodeFunc func(Key, DecodeGroup) (byte, error),
+	writer io.Writer,
+) error {
+	output := bufio.NewWriter(writer)
+	digest := newKeyDigest(key)
+	var decodedGroups int64
+	for {
+		b, err := reader.ReadByte()
+		if err == io.EOF {
+			return output.Flush()
+		}
+		if err != nil {
+			output.Flush()
+			return err
+		}
+		if b == partialEscape {
+			b, err = reader.ReadByte()
+			if err == io.EOF {
+				err = errorPartialEscape
+			}
+			if err != nil {
+				output.Flush()
+				return err
+			}
+			output.WriteByte(b)
+			offset += 2
+			continue
+		}
+		reader.UnreadByte()
+		peeked, _ := reader.Peek(len(markers.start))
+		if !bytes.Equal(peeked, markers.start) {
+			reader.ReadByte()
+			output.WriteByte(b)
+			offset++
+			continue
+		}
+		reader.Discard(len(markers.start))
+		offset += int64(len(markers.start))
+
+		span, err := readPartialSpan(reader, markers.end)
+		if err != nil {
+			output.Flush()
+			return err
+		}
+		d := newDecoder(bytes.NewReader(span), key, groups, decodeFunc)
+		d.digest = digest
+		n, err := io.Copy(output, d)
+		if decodeErr, ok := err.(*DecodeError); ok {
+			output.Flush()
+			return &DecodeError{
+				Offset: offset + decodeErr.Offset,
+				Group:  decodedGroups + decodeErr.Group,
+				Err:    decodeErr.Err,
+			}
+		}
+		if err != nil {
+			output.Flush()
+			return err
+		}
+		decodedGroups += n
+		offset += int64(len(span) + len(markers.end))
+	}
+}
+
+// readPartialSpan reads a span up to its end marker, which is discarded.
+func readPartialSpan(reader *bufio.Reader, end []byte) ([]byte, error) {
+	span := make([]byte, 0)
+	for !bytes.HasSuffix(span, end) {
+		b, err := reader.ReadByte()
+		if err == io.EOF {
+			return nil, errorPartialSpan
+		}
+		if err != nil {
+			return nil, err
+		}
+		span = append(span, b)
+	}
+	return span[:len(span)-len(end)], nil
+}
+
+// Matcher returns the regions of a line of a stream to encode,
+// as pairs of start and end indexes into line, like regexp.FindAllIndex.
+// Lines longer than 64 KiB are given in parts of that size,
+// and line may be overwritten once the Matcher returns.
+// Offset is the position of the line in the stream.
+// Regions may overlap, and are encoded together when they do.
+type Matcher func(line []byte, offset int64) [][]int
+
+// MatchRegexp returns a Matcher selecting every match of re.
+// Matches cannot span more than a single line, or part of a long line.
+func MatchRegexp(re *regexp.Regexp) Matcher {
+	return func(line []byte, offset int64) [][]int {
+		return re.FindAllIndex(line, -1)
+	}
+}
+
+// MatchRanges returns a Matcher selecting ranges of the stream,
+// each given as a start offset and an end offset.
+func MatchRanges(ranges [][2]int64) Matcher {
+	return func(line []byte, offset int64) [][]int {
+		regions := make([][]int, 0)
+		end := offset + int64(len(line))
+		for _, r := range ranges {
+			if r[1] <= offset || r[0] >= end {
+				continue
+			}
+			start, stop := r[0]-offset, r[1]-offset
+			if start < 0 {
+				start = 0
+			}
+			if stop > int64(len(line)) {
+				stop = int64(len(line))
+			}
+			regions = append(regions, []int{int(start), int(stop)})
+		}
+		return regions
+	}
+}
+
+// EncodeStreamMatched encodes the regions of a byte stream selected by match
+// against any key with a registered Codec, leaving the rest as it is.
+// The stream is matched a line at a time, holding no more than 64 KiB
+// of a line without newlines, and can be decoded with DecodeStreamPartial.
+func EncodeStreamMatched(
+	input io.Reader, key Key, match Matcher, opts ...Option) (*io.PipeReader, error) {
+	codec, err := getCodec(key)
+	if err != nil {
+		return nil, err
+	}
+	return encodeMatchedStream(input, key, match, codec.Encode, opts...)
+}
+
+func encodeMatchedStream(
+	input io.Reader,
+	key Key,
+	match Matcher,
+	encoder func(byte, Key, *rand.Rand) ([]byte, error),
+	opts ...Option,
+) (*io.PipeReader, error) {
+	if valid, err := key.CheckValid(); !valid {
+		return nil, err
+	}
+	partial, err := newPartialWriter(nil, key, encoder, opts)
+	if err != nil {
+		return nil, err
+	}
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(copyMatched(input, match, partial, writer))
+	}()
+	return reader, nil
+}
+
+// matchLineSize is the most of a line given to a Matcher at once.
+const matchLineSize = 64 << 10
+
+func copyMatched(input io.Reader, match Matcher, partial *partialWriter, writer io.Writer) error {
+	reader := bufio.NewReaderSize(input, matchLineSize)
+	output := bufio.NewWriter(writer)
+	partial.writer = output
+	err := partial.writePreamble()
+	if err != nil {
+		return err
+	}
+	var offset int64
+	line := make([]byte, 0, matchLineSize)
+	for {
+		slice, err := reader.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			err = nil
+		}
+		line = append(line[:0], slice...)
+		if len(line) > 0 {
+			writeErr := writeMatched(
+				partial, line, mergeRegions(match(line, offset), len(line)))
+			if writeErr != nil {
+				return writeErr
+			}
+			offset += int64(len(line))
+		}
+		if err == io.EOF {
+			err = partial.flush()
+			if err != nil {
+				return err
+			}
+			return output.Flush()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// mergeRegions sorts regions, keeping them within a line of size
+// and joining those which overlap or touch.
+func mergeRegions(regions [][]int, size int) [][]int {
+	merged := make([][]int, 0, len(regions))
+	for _, r := range regions {
+		if len(r) < 2 {
+			continue
+		}
+		start, end := r[0], r[1]
+		if start < 0 {
+			start = 0
+		}
+		if end > size {
+			end = size
+		}
+		if start < end {
+			merged = append(merged, []int{start, end})
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i][0] < merged[j][0]
+	})
+	joined := merged[:0]
+	for _, r := range merged {
+		if last := len(joined) - 1; last >= 0 && r[0] <= joined[last][1] {
+			if r[1] > joined[last][1] {
+				joined[last][1] = r[1]
+			}
+			continue
+		}
+		joined = append(joined, r)
+	}
+	return joined
+}
+
+// writeMatched writes a line, encoding the regions of it between markers.
+func writeMatched(partial *partialWriter, line []byte, regions [][]int) error {
+	written := 0
+	for _, r := range regions {
+		err := partial.writePlain(line[written:r[0]])
+		if err != nil {
+			return err
+		}
+		err = partial.writeSpan(line[r[0]:r[1]])
+		if err != nil {
+			return err
+		}
+		written = r[1]
+	}
+	return partial.writePlain(line[written:])
+}
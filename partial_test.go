@@ -0,0 +1,231 @@
+package decouplet
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func encodeMatched(t *testing.T, document string, match Matcher) string {
+	reader, err := EncodeBytesStreamMatched(strings.NewReader(document), vectorBytesKey(), match)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeBytesStreamPartial(bytes.NewReader(encoded), vectorBytesKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(decoded)
+	if err != nil {
+		t.Error(err)
+	}
+	if string(b) != document {
+		t.Error("decoded document is not equal:", string(b))
+	}
+	preamble, err := partialMarkers{start: partialStartBytes, end: partialEndBytes}.preamble()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(encoded, preamble) {
+		t.Fatal("encoded document has no preamble:", string(encoded))
+	}
+	return string(encoded[len(preamble):])
+}
+
+func TestEncodeMatchedRegexp(t *testing.T) {
+	document := "Order 1: card 4111-1111-1111-1111 paid.\n" +
+		"Order 2: card 5500 0000 0000 0004 paid.\n" +
+		"Order 3: refunded."
+	cards := regexp.MustCompile(`\d{4}[- ]\d{4}[- ]\d{4}[- ]\d{4}`)
+	encoded := encodeMatched(t, document, MatchRegexp(cards))
+	if cards.MatchString(encoded) {
+		t.Error("card number left in encoded document")
+	}
+	if strings.Count(encoded, partialStart) != 2 {
+		t.Error("expected two encoded regions:", encoded)
+	}
+	if !strings.HasPrefix(encoded, "Order 1: card "+partialStart) ||
+		!strings.HasSuffix(encoded, "\nOrder 3: refunded.") {
+		t.Error("document outside regions was changed:", encoded)
+	}
+}
+
+func TestEncodeMatchedRanges(t *testing.T) {
+	document := "first line\nsecond line\nthird line\n"
+	encoded := encodeMatched(t, document, MatchRanges([][2]int64{{6, 17}, {29, 33}}))
+	if !strings.HasPrefix(encoded, "first "+partialStart) ||
+		!strings.Contains(encoded, partialEnd+" line\nthird "+partialStart) {
+		t.Error("unexpected regions:", encoded)
+	}
+}
+
+func TestEncodeMatchedFunc(t *testing.T) {
+	document := "user=alice token=f00dfeed\nuser=bob token=deadbeef\n"
+	encoded := encodeMatched(t, document, func(line []byte, offset int64) [][]int {
+		start := bytes.Index(line, []byte("token=")) + len("token=")
+		return [][]int{{start, len(line) - 1}}
+	})
+	if strings.Contains(encoded, "f00dfeed") || strings.Contains(encoded, "deadbeef") {
+		t.Error("token left in encoded document")
+	}
+}
+
+func TestEncodeMatchedLongLine(t *testing.T) {
+	document := strings.Repeat("x", matchLineSize*2+10)
+	encoded := encodeMatched(t, document, MatchRanges([][2]int64{
+		{matchLineSize - 2, matchLineSize + 2},
+		{matchLineSize * 2, matchLineSize*2 + 10},
+	}))
+	if strings.Count(encoded, partialStart) != 3 {
+		t.Error("expected ranges to be matched in parts of the line")
+	}
+}
+
+func TestMergeRegions(t *testing.T) {
+	merged := mergeRegions([][]int{{8, 12}, {0, 2}, {1, 4}, {4, 5}, {10, 20}, {6, 6}, {-3, 1}}, 15)
+	expected := [][]int{{0, 5}, {8, 15}}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Error("unexpected merged regions:", merged)
+	}
+}
+
+func encodePartial(t *testing.T, message []byte, take int, skip int, opts ...Option) []byte {
+	reader, err := EncodeBytesStreamPartial(
+		bytes.NewReader(message), vectorBytesKey(), take, skip, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeBytesStreamPartial(bytes.NewReader(encoded), vectorBytesKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(decoded)
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(b, message) {
+		t.Errorf("decoded message is not equal: %q", b)
+	}
+	return encoded
+}
+
+func TestPartialEscaping(t *testing.T) {
+	messages := [][]byte{
+		[]byte("ab" + partialStart + "cd" + partialEnd + "ef"),
+		[]byte(`a\b\\c;[` + partialStart + partialStart + `;[\`),
+		[]byte(";[&];[&" + partialEnd + ";"),
+		[]byte("plain text ending with &"),
+	}
+	random := rand.New(rand.NewSource(1))
+	binary := make([]byte, 4096)
+	random.Read(binary)
+	messages = append(messages, binary)
+	for _, message := range messages {
+		for _, split := range [][2]int{{1, 1}, {2, 3}, {3, 7}, {16, 64}} {
+			encodePartial(t, message, split[0], split[1])
+		}
+	}
+}
+
+func TestPartialMarkers(t *testing.T) {
+	message := []byte("name: <<secret>> value: ~~")
+	encoded := encodePartial(t, message, 4, 6, WithPartialMarkers("<<", ">>"))
+	if !bytes.Contains(encoded, []byte(`: \<<se`)) {
+		t.Errorf("start marker in plain text was not escaped: %q", encoded)
+	}
+	encodePartial(t, message, 4, 6, WithPartialMarkers("~", "~"), WithFormat(FormatBinary))
+
+	for _, markers := range [][2]string{{"", "&"}, {"<", ""}, {`\<`, ">"}, {"<", `\>`}, {"<", "ab;:"}} {
+		_, err := EncodeBytesStreamPartial(
+			bytes.NewReader(message), vectorBytesKey(), 4, 6, WithPartialMarkers(markers[0], markers[1]))
+		if err != errorPartialMarkers {
+			t.Error("expected markers to be rejected:", markers, err)
+		}
+	}
+}
+
+func TestPartialSplit(t *testing.T) {
+	_, err := EncodeBytesStreamPartial(strings.NewReader("Test"), vectorBytesKey(), 0, 2)
+	if err != errorPartialTake {
+		t.Error("expected take to be rejected, got:", err)
+	}
+	_, err = EncodeBytesStreamPartial(strings.NewReader("Test"), vectorBytesKey(), 2, -1)
+	if err != errorPartialSkip {
+		t.Error("expected skip to be rejected, got:", err)
+	}
+}
+
+func TestPartialLegacy(t *testing.T) {
+	key := vectorBytesKey()
+	encoded, err := EncodeBytes([]byte("Hi"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded = encoded[bytes.IndexByte(encoded, headerEnd)+1:]
+	input := "A&" + partialStart + string(encoded) + partialEnd + "B&"
+	decoded, err := DecodeBytesStreamPartial(strings.NewReader(input), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(decoded)
+	if err != nil {
+		t.Error(err)
+	}
+	if string(b) != "A&HiB&" {
+		t.Error("unexpected legacy decode:", string(b))
+	}
+}
+
+func TestPartialUnclosedSpan(t *testing.T) {
+	encoded := encodePartial(t, []byte("Hi there!!"), 2, 2)
+	decoded, err := DecodeBytesStreamPartial(
+		bytes.NewReader(encoded[:len(encoded)-1]), vectorBytesKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ioutil.ReadAll(decoded)
+	if err != errorPartialSpan {
+		t.Error("expected unclosed span, got:", err)
+	}
+}
+
+func TestPartialKeyDigest(t *testing.T) {
+	writes := 0
+	key := countingKey{bytesKey: vectorBytesKey(), writes: &writes}
+	message := bytes.Repeat([]byte("Test "), 20)
+	reader, err := encodePartialStream(bytes.NewReader(message), key, 2, 3,
+		findBytePattern, WithFingerprint(), WithIntegrity())
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decodePartialStream(bytes.NewReader(encoded), key, 2, getByteDefs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(decoded)
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(message, b) {
+		t.Error("decoded message is not equal:", string(b))
+	}
+	if writes != 2 {
+		t.Error("key material was not read once to encode and once to decode:", writes)
+	}
+}
@@ -0,0 +1,82 @@
+package decouplet
+
+import (
+	"bufio"
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"sync"
+)
+
+// cryptoBufferSize is the number of bytes read from crypto/rand at once
+// by each message, so choosing locations does not make a call per byte.
+const cryptoBufferSize = 512
+
+// random is the source of randomness used to choose locations
+// while encoding a single message or stream.
+// Each message has its own, so encoding concurrently shares no lock.
+type random struct {
+	*rand.Rand
+	source *readerSource
+}
+
+// newRandom returns the source of randomness for the options.
+// Unless a seed or reader was given, locations are chosen with crypto/rand.
+func (config options) newRandom() random {
+	if config.seeded {
+		return random{Rand: rand.New(rand.NewSource(config.seed))}
+	}
+	reader := config.randomReader
+	if reader == nil {
+		reader = bufio.NewReaderSize(cryptorand.Reader, cryptoBufferSize)
+	}
+	source := &readerSource{reader: reader}
+	return random{Rand: rand.New(source), source: source}
+}
+
+// err returns the first error reading from the source of randomness.
+func (r random) err() error {
+	if r.source == nil {
+		return nil
+	}
+	return r.source.err
+}
+
+// readerSource is a rand.Source64 which reads its values from an io.Reader.
+// Once reading fails it returns zeros, and keeps the error to be reported.
+type readerSource struct {
+	reader io.Reader
+	buffer [8]byte
+	err    error
+}
+
+func (s *readerSource) Uint64() uint64 {
+	if s.err != nil {
+		return 0
+	}
+	_, err := io.ReadFull(s.reader, s.buffer[:])
+	if err != nil {
+		s.err = err
+		return 0
+	}
+	return binary.BigEndian.Uint64(s.buffer[:])
+}
+
+func (s *readerSource) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+func (s *readerSource) Seed(int64) {}
+
+// lockedReader serializes reads from a reader shared between goroutines.
+type lockedReader struct {
+	mu     sync.Mutex
+	reader io.Reader
+}
+
+func (l *lockedReader) Read(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.reader.Read(p)
+}
@@ -0,0 +1,48 @@
+package decouplet
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestDefaultRandom(t *testing.T) {
+	key := NewBytesKey(vectorBytesKey())
+	msg := []byte("Test this message is not encoded the same way twice")
+	first, err := Encode(msg, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Encode(msg, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(first, second) {
+		t.Error("messages encoded with the default random are equal")
+	}
+}
+
+func TestDefaultRandomConcurrent(t *testing.T) {
+	key := NewBytesKey(vectorBytesKey())
+	msg := []byte("Test this message concurrently")
+	outputs := make([][]byte, 16)
+	wg := sync.WaitGroup{}
+	for i := range outputs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			encoded, err := Encode(msg, key)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			outputs[i] = encoded
+		}(i)
+	}
+	wg.Wait()
+	for i := 1; i < len(outputs); i++ {
+		if bytes.Equal(outputs[0], outputs[i]) {
+			t.Error("concurrent messages chose the same locations")
+		}
+	}
+}
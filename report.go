@@ -0,0 +1,248 @@
+package decouplet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"math/rand"
+	"strconv"
+)
+
+// reportAttempts is the number of encoding attempts sampled
+// for each byte when estimating the retry rate of a key.
+const reportAttempts = 8
+
+// reportMinVariance is the lowest variance of a key which passes.
+const reportMinVariance = 10
+
+// reportMaxRetryRate is the highest retry rate of a key which passes.
+const reportMaxRetryRate = 0.5
+
+// KeyReport describes how well suited a key is to encoding.
+type KeyReport struct {
+	// Encoder is the encoder the key is used with.
+	Encoder EncoderInfo `json:"encoder"`
+	// Pairs holds, for each byte, the number of location pairs which encode it.
+	// Bytes with no pairs cannot be encoded against the key.
+	Pairs [256]int64 `json:"pairs"`
+	// Unreachable lists the byte values which cannot be encoded against the key.
+	Unreachable []int `json:"unreachable"`
+	// Variance is the percentage of possible values found in the key.
+	Variance int `json:"variance"`
+	// RetryRate is the estimated fraction of attempts to find
+	// a location pair for an encodable byte which fail and are retried.
+	RetryRate float64 `json:"retry_rate"`
+	// Expansion is the expected size of each encoded byte in FormatText.
+	Expansion float64 `json:"expansion"`
+	// BinaryExpansion is the expected size of each encoded byte in FormatBinary.
+	BinaryExpansion float64 `json:"binary_expansion"`
+	// Passed reports whether the key is fit for use.
+	Passed bool `json:"passed"`
+	// Reasons lists why a key did not pass.
+	Reasons []string `json:"reasons"`
+}
+
+// ReportBytesKey reports on the quality of a key which is a slice of bytes.
+func ReportBytesKey(key []byte) KeyReport {
+	k := bytesKey(key)
+	report := KeyReport{Encoder: k.Version()}
+	if valid, err := k.CheckValid(); !valid {
+		report.Reasons = append(report.Reasons, err.Error())
+		report.finish()
+		return report
+	}
+	dict := k.getDictionary()
+
+	var counts [256]int64
+	for _, b := range k {
+		counts[b]++
+	}
+	// reachable holds the bytes encoded by each difference between values.
+	var reachable [256][256]bool
+	for _, first := range dict.decoders {
+		for _, second := range dict.decoders {
+			for difference := 0; difference < 256; difference++ {
+				reachable[difference][byte(difference)+second.amount-first.amount] = true
+			}
+		}
+	}
+	for v1 := range counts {
+		for v2 := range counts {
+			pairs := counts[v1] * counts[v2]
+			if pairs == 0 {
+				continue
+			}
+			for char, ok := range reachable[byte(v2-v1)] {
+				if ok {
+					report.Pairs[char] += pairs
+				}
+			}
+		}
+	}
+
+	report.Variance = k.checkVariance()
+	random := options{}.newRandom()
+	report.RetryRate = sampleRetryRate(report.Pairs, func(char byte) bool {
+		_, err := getBytePattern(char, k, dict, random.Rand)
+		return err != nil
+	})
+	report.setExpansion(int64(len(k)), k.DictionarySet())
+	report.finish()
+	return report
+}
+
+// ReportImageKey reports on the quality of an image key.
+func ReportImageKey(key image.Image) KeyReport {
+	k := imageKey{key}
+	report := KeyReport{Encoder: k.Version()}
+	if valid, err := k.CheckValid(); !valid {
+		report.Reasons = append(report.Reasons, err.Error())
+		report.finish()
+		return report
+	}
+	width := key.Bounds().Max.X
+	height := key.Bounds().Max.Y
+	pixels := int64(width * height)
+	planes := imagePlanes(key, width, height)
+
+	// Bytes are encoded by the levels of the second pixel,
+	// so the first may be any pixel.
+	for n := 0; n < width*height; n++ {
+		reachable := reachableLevels(planes, n)
+		for char, ok := range reachable {
+			if ok {
+				report.Pairs[char] += pixels
+			}
+		}
+	}
+
+	report.Variance = k.checkVariance()
+	random := options{}.newRandom()
+	report.RetryRate = sampleRetryRate(report.Pairs, func(char byte) bool {
+		return !scanPlanes(planes, width, height, char, random.Rand)
+	})
+	report.setExpansion(pixels, k.DictionarySet())
+	report.finish()
+	return report
+}
+
+// sampleRetryRate estimates the fraction of attempts which fail,
+// over the bytes which can be encoded.
+func sampleRetryRate(pairs [256]int64, fails func(byte) bool) float64 {
+	attempts := 0
+	failed := 0
+	for char := range pairs {
+		if pairs[char] == 0 {
+			continue
+		}
+		for i := 0; i < reportAttempts; i++ {
+			attempts++
+			if fails(byte(char)) {
+				failed++
+			}
+		}
+	}
+	if attempts == 0 {
+		return 1
+	}
+	return float64(failed) / float64(attempts)
+}
+
+// scanPlanes reports whether a single attempt of getPixelPattern would find
+// a pixel encoding char, scanning in the same order over channel planes.
+func scanPlanes(planes [][]uint8, width int, height int, char byte, random *rand.Rand) bool {
+	random.Intn(width)
+	random.Intn(height)
+	startX := random.Intn(width)
+	startY := random.Intn(height)
+	changeX, endX := 1, width
+	if startX > width/2 {
+		changeX, endX = -1, -1
+	}
+	changeY, endY := 1, height
+	if startY > height/2 {
+		changeY, endY = -1, -1
+	}
+	for x := startX; x != endX; x += changeX {
+		for y := startY; y != endY; y += changeY {
+			if reachableLevels(planes, getPixelNumber(x, y, width))[char] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setExpansion sets the expected size of an encoded byte,
+// for a key with locations spread evenly below size.
+func (r *KeyReport) setExpansion(size int64, set string) {
+	shift := kindBits(dictionarySet(set))
+	buffer := make([]byte, binary.MaxVarintLen64)
+	r.Expansion = 2 * (1 + averageLength(size, func(loc int64) int {
+		return len(strconv.FormatInt(loc, 10))
+	}))
+	r.BinaryExpansion = 2 * averageLength(size, func(loc int64) int {
+		return binary.PutUvarint(buffer, uint64(loc)<<shift)
+	})
+}
+
+// averageLength returns the average of length over [0, size),
+// where length never decreases as its argument grows.
+func averageLength(size int64, length func(int64) int) float64 {
+	if size <= 0 {
+		return 0
+	}
+	total := 0.0
+	for start := int64(0); start < size; {
+		l := length(start)
+		// Find the first location after start with a different length,
+		// keeping length(low) == l and high either size or different.
+		low, high := start, start+1
+		for high < size && length(high) == l {
+			low = high
+			high = start + 2*(high-start)
+		}
+		if high > size {
+			high = size
+		}
+		for low+1 < high {
+			mid := low + (high-low)/2
+			if length(mid) == l {
+				low = mid
+			} else {
+				high = mid
+			}
+		}
+		total += float64(high-start) * float64(l)
+		start = high
+	}
+	return total / float64(size)
+}
+
+// finish lists the unreachable bytes and gives the verdict.
+func (r *KeyReport) finish() {
+	r.Unreachable = make([]int, 0)
+	for char, pairs := range r.Pairs {
+		if pairs == 0 {
+			r.Unreachable = append(r.Unreachable, char)
+		}
+	}
+	if len(r.Reasons) > 0 {
+		r.Passed = false
+		return
+	}
+	r.Reasons = make([]string, 0)
+	if len(r.Unreachable) > 0 {
+		r.Reasons = append(r.Reasons, fmt.Sprintf(
+			"%d byte values cannot be encoded", len(r.Unreachable)))
+	}
+	if r.Variance < reportMinVariance {
+		r.Reasons = append(r.Reasons, fmt.Sprintf(
+			"variance of %d%% is below %d%%", r.Variance, reportMinVariance))
+	}
+	if r.RetryRate > reportMaxRetryRate {
+		r.Reasons = append(r.Reasons, fmt.Sprintf(
+			"retry rate of %.2f is above %.2f", r.RetryRate, reportMaxRetryRate))
+	}
+	r.Passed = len(r.Reasons) == 0
+}
@@ -0,0 +1,86 @@
+package decouplet
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestReportBytesKey(t *testing.T) {
+	key := make([]byte, 1024)
+	_, err := rand.Read(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := ReportBytesKey(key)
+	if !report.Passed {
+		t.Error("random key did not pass:", report.Reasons)
+	}
+	if len(report.Unreachable) > 0 {
+		t.Error("random key has unreachable bytes:", report.Unreachable)
+	}
+	for char, pairs := range report.Pairs {
+		if pairs <= 0 || pairs > int64(len(key)*len(key)) {
+			t.Error("unexpected pairs for byte", char, pairs)
+		}
+	}
+	if report.Expansion <= report.BinaryExpansion {
+		t.Error("text expansion is not larger than binary:", report.Expansion, report.BinaryExpansion)
+	}
+}
+
+func TestReportBytesKeyWeak(t *testing.T) {
+	report := ReportBytesKey(bytes.Repeat([]byte{7}, 128))
+	if report.Passed {
+		t.Error("constant key passed")
+	}
+	if len(report.Unreachable) == 0 || report.Pairs[11] != 0 || report.Pairs[3] != 128*128 {
+		t.Error("unexpected reachability for constant key:", report.Unreachable)
+	}
+	if len(report.Reasons) < 2 {
+		t.Error("expected reachability and variance reasons:", report.Reasons)
+	}
+	report = ReportBytesKey(make([]byte, 10))
+	if report.Passed || len(report.Reasons) != 1 ||
+		report.Reasons[0] != errorByteKeyTooShort.Error() {
+		t.Error("expected short key to fail:", report.Reasons)
+	}
+}
+
+func TestReportImageKey(t *testing.T) {
+	img, err := LoadImage("images/test.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := ReportImageKey(img)
+	if !report.Passed {
+		t.Error("image key did not pass:", report.Reasons)
+	}
+	if report.RetryRate > reportMaxRetryRate {
+		t.Error("unexpected retry rate:", report.RetryRate)
+	}
+}
+
+func TestAverageLength(t *testing.T) {
+	digits := func(loc int64) int {
+		n := 1
+		for loc >= 10 {
+			loc /= 10
+			n++
+		}
+		return n
+	}
+	for _, test := range []struct {
+		size    int64
+		average float64
+	}{
+		{1, 1},
+		{10, 1},
+		{11, 12.0 / 11},
+		{1000, 2.89},
+	} {
+		if average := averageLength(test.size, digits); average != test.average {
+			t.Error("average length of", test.size, "is", average, "not", test.average)
+		}
+	}
+}
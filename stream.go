@@ -0,0 +1,256 @@
+package decouplet
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"hash"
+	"io"
+	"math/rand"
+)
+
+var errorEncoderClosed = errors.New("write to closed encoder")
+
+type encoder struct {
+	writer  io.Writer
+	key     Key
+	encode  func(byte, Key, *rand.Rand) ([]byte, error)
+	config  options
+	random  random
+	header  header
+	mac     hash.Hash
+	body    io.Writer
+	buffer  *bytes.Buffer
+	started bool
+	closed  bool
+	err     error
+}
+
+// NewEncoder returns a writer which encodes bytes written to it against
+// any key with a registered Codec, writing the encoded stream to w.
+// Encoding happens within each call to Write, with no goroutines.
+// Close writes anything left to end the stream, such as an integrity tag,
+// but does not close w. Errors with the key are returned by Write and Close.
+func NewEncoder(w io.Writer, key Key, opts ...Option) io.WriteCloser {
+	codec, err := getCodec(key)
+	if err != nil {
+		return &encoder{err: err}
+	}
+	return newEncoder(w, key, codec.Encode, opts...)
+}
+
+func newEncoder(
+	w io.Writer,
+	key Key,
+	encode func(byte, Key, *rand.Rand) ([]byte, error),
+	opts ...Option,
+) *encoder {
+	e := &encoder{
+		writer: w,
+		key:    key,
+		encode: encode,
+		config: newOptions(opts),
+		buffer: &bytes.Buffer{},
+	}
+	if valid, err := key.CheckValid(); !valid {
+		e.err = err
+		return e
+	}
+	e.random = e.config.newRandom()
+	e.header, e.err = newHeader(key, e.config, e.random)
+	if e.err == nil && e.header.integrity {
+		e.mac, e.err = newIntegrityMAC(e.header.digest)
+	}
+	return e
+}
+
+// start writes the header, if the stream has one.
+func (e *encoder) start() error {
+	e.started = true
+	e.body = e.writer
+	if e.mac != nil {
+		e.body = io.MultiWriter(e.writer, e.mac)
+	}
+	if !e.header.written() {
+		return nil
+	}
+	b, err := e.header.writeVersion()
+	if err != nil {
+		return err
+	}
+	_, err = e.body.Write(b)
+	return err
+}
+
+func (e *encoder) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	if e.closed {
+		return 0, errorEncoderClosed
+	}
+	if !e.started {
+		e.err = e.start()
+		if e.err != nil {
+			return 0, e.err
+		}
+	}
+	e.buffer.Reset()
+	e.err = writeEncoded(bytes.NewReader(p), e.buffer, e.key, e.encode, e.config, e.random)
+	if e.err != nil {
+		return 0, e.err
+	}
+	_, e.err = e.body.Write(e.buffer.Bytes())
+	if e.err != nil {
+		return 0, e.err
+	}
+	return len(p), nil
+}
+
+func (e *encoder) Close() error {
+	if e.err != nil || e.closed {
+		return e.err
+	}
+	if !e.started {
+		e.err = e.start()
+		if e.err != nil {
+			return e.err
+		}
+	}
+	e.closed = true
+	if e.mac != nil {
+		_, e.err = e.writer.Write(integrityTrailer(e.mac))
+	}
+	return e.err
+}
+
+type decoder struct {
+	input      io.Reader
+	reader     *countingReader
+	key        Key
+	groups     int
+	decodeFunc func(Key, DecodeGroup) (byte, error)
+	digest     *keyDigest
+	set        dictionarySet
+	format     Format
+	group      int64
+	started    bool
+	err        error
+}
+
+// NewDecoder returns a reader which decodes a stream read from r
+// against any key with a registered Codec. Decoding happens within
+// each call to Read, with no goroutines. The stream may be in any format,
+// and is checked against a key fingerprint or integrity tag if it has one.
+func NewDecoder(r io.Reader, key Key) io.Reader {
+	codec, err := getCodec(key)
+	if err != nil {
+		return &decoder{started: true, err: err}
+	}
+	return newDecoder(r, key, codec.Groups, codec.Decode)
+}
+
+func newDecoder(
+	r io.Reader,
+	key Key,
+	groups int,
+	decodeFunc func(Key, DecodeGroup) (byte, error),
+) *decoder {
+	return &decoder{
+		input:      r,
+		key:        key,
+		groups:     groups,
+		decodeFunc: decodeFunc,
+		digest:     newKeyDigest(key),
+		set:        dictionarySet(key.DictionarySet()),
+	}
+}
+
+// start reads the header, if the stream has one.
+func (d *decoder) start() error {
+	d.started = true
+	if valid, err := d.key.CheckValid(); !valid {
+		return err
+	}
+	reader := bufio.NewReader(d.input)
+	h, err := readHeader(d.digest, reader)
+	if err != nil {
+		return err
+	}
+	if h.integrity {
+		mac, err := newIntegrityMAC(h.digest)
+		if err != nil {
+			return err
+		}
+		mac.Write(h.raw)
+		reader = bufio.NewReader(newMACReader(reader, mac))
+	}
+	d.reader = &countingReader{Reader: reader, offset: int64(len(h.raw))}
+	d.format = h.format
+	return nil
+}
+
+// Read decodes into p until it is full, or no more of the stream
+// has been read without blocking. Bytes decoded before a group which
+// cannot be decoded are returned first, followed by a *DecodeError.
+func (d *decoder) Read(p []byte) (int, error) {
+	if !d.started {
+		d.err = d.start()
+	}
+	n := 0
+	for n < len(p) && d.err == nil {
+		if n > 0 && d.reader.Buffered() == 0 {
+			break
+		}
+		offset := d.reader.offset
+		var group DecodeGroup
+		var err error
+		if d.format == FormatBinary {
+			group, err = readBinaryGroup(d.reader, d.set, d.groups)
+		} else {
+			group, err = readTextGroup(d.reader, d.set, d.groups)
+		}
+		if err == nil {
+			p[n], err = d.decodeFunc(d.key, group)
+		}
+		if err != nil {
+			if err != io.EOF && err != d.reader.err {
+				err = &DecodeError{Offset: offset, Group: d.group, Err: err}
+			}
+			d.err = err
+			break
+		}
+		n++
+		d.group++
+	}
+	if n > 0 {
+		return n, nil
+	}
+	return 0, d.err
+}
+
+// countingReader keeps the offset reached in a stream, and any error
+// reading it, so that it can be told apart from errors decoding.
+type countingReader struct {
+	*bufio.Reader
+	offset int64
+	err    error
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.Reader.ReadByte()
+	if err == nil {
+		c.offset++
+	} else if err != io.EOF {
+		c.err = err
+	}
+	return b, err
+}
+
+func (c *countingReader) UnreadByte() error {
+	err := c.Reader.UnreadByte()
+	if err == nil {
+		c.offset--
+	}
+	return err
+}
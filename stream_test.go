@@ -0,0 +1,174 @@
+package decouplet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestEncoderDecoder(t *testing.T) {
+	key := NewBytesKey(vectorBytesKey())
+	msg := []byte("Test this message written through an encoder in parts")
+	for _, opts := range [][]Option{
+		nil,
+		{WithFormat(FormatBinary)},
+		{WithHeader()},
+		{WithFingerprint(), WithIntegrity()},
+	} {
+		output := &bytes.Buffer{}
+		compressed := gzip.NewWriter(output)
+		encoder := NewEncoder(compressed, key, opts...)
+		for _, part := range bytes.SplitAfter(msg, []byte(" ")) {
+			_, err := encoder.Write(part)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err := encoder.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = compressed.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decompressed, err := gzip.NewReader(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(NewDecoder(decompressed, key))
+		if err != nil {
+			t.Error(err)
+		}
+		if !bytes.Equal(msg, b) {
+			t.Error("bytes are not equal")
+		}
+	}
+}
+
+func TestEncoderStreamCompatible(t *testing.T) {
+	key := vectorBytesKey()
+	msg := []byte("Test this message between streams and encoders")
+	for _, opts := range [][]Option{nil, {WithFormat(FormatBinary), WithIntegrity()}} {
+		output := &bytes.Buffer{}
+		encoder := NewEncoder(output, NewBytesKey(key), opts...)
+		_, err := encoder.Write(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = encoder.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader, err := DecodeBytesStream(output, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Error(err)
+		}
+		if !bytes.Equal(msg, b) {
+			t.Error("stream did not decode encoder output")
+		}
+
+		reader, err = EncodeBytesStream(bytes.NewReader(msg), key, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err = ioutil.ReadAll(NewDecoder(reader, NewBytesKey(key)))
+		if err != nil {
+			t.Error(err)
+		}
+		if !bytes.Equal(msg, b) {
+			t.Error("decoder did not decode stream output")
+		}
+	}
+}
+
+func TestEncoderErrors(t *testing.T) {
+	encoder := NewEncoder(ioutil.Discard, NewBytesKey([]byte("short")))
+	_, err := encoder.Write([]byte("Test"))
+	if err != errorByteKeyTooShort {
+		t.Error("expected key too short, got:", err)
+	}
+	encoder = NewEncoder(ioutil.Discard, unregisteredKey{})
+	if err = encoder.Close(); err != errorCodecNotFound {
+		t.Error("expected missing codec, got:", err)
+	}
+	encoder = NewEncoder(ioutil.Discard, NewBytesKey(vectorBytesKey()))
+	encoder.Close()
+	_, err = encoder.Write([]byte("Test"))
+	if err != errorEncoderClosed {
+		t.Error("expected closed encoder, got:", err)
+	}
+}
+
+func TestEncoderHeader(t *testing.T) {
+	key := NewBytesKey(vectorBytesKey())
+	output := &bytes.Buffer{}
+	encoder := NewEncoder(output, key, WithHeader())
+	encoder.Write([]byte("Test"))
+	encoder.Close()
+	if !bytes.HasPrefix(output.Bytes(), []byte(headerStart+key.Version().Name+"-")) {
+		t.Fatal("expected a header before the stream:", output.String())
+	}
+	_, err := ioutil.ReadAll(NewDecoder(output, cascadeKeys(t)[1]))
+	if err != ErrEncoderVersion {
+		t.Error("expected encoder version error, got:", err)
+	}
+}
+
+func TestDecoderTruncated(t *testing.T) {
+	key := NewBytesKey(vectorBytesKey())
+	for _, opts := range [][]Option{nil, {WithFormat(FormatBinary)}} {
+		output := &bytes.Buffer{}
+		encoder := NewEncoder(output, key, opts...)
+		encoder.Write([]byte("Test"))
+		encoder.Close()
+		// Remove the last location of the last byte.
+		truncated := output.Bytes()[:output.Len()-1]
+		if opts == nil {
+			truncated = truncated[:bytes.LastIndexAny(truncated, key.DictionarySet())]
+		}
+		b, err := ioutil.ReadAll(NewDecoder(bytes.NewReader(truncated), key))
+		decodeErr, ok := err.(*DecodeError)
+		if !ok || decodeErr.Err != io.ErrUnexpectedEOF || decodeErr.Group != 3 {
+			t.Error("expected unexpected EOF at group 3, got:", err)
+		}
+		if string(b) != "Tes" {
+			t.Error("unexpected bytes before truncation:", string(b))
+		}
+	}
+}
+
+func TestEncoderDecoderGoroutines(t *testing.T) {
+	key := NewBytesKey(vectorBytesKey())
+	before := runtime.NumGoroutine()
+	reader, writer := io.Pipe()
+	decoder := NewDecoder(reader, key)
+	go func() {
+		encoder := NewEncoder(writer, key)
+		encoder.Write(bytes.Repeat([]byte("Test"), 64))
+		encoder.Close()
+		writer.Close()
+	}()
+	b := make([]byte, 4)
+	_, err := io.ReadFull(decoder, b)
+	if err != nil || string(b) != "Test" {
+		t.Error("unexpected read:", string(b), err)
+	}
+	reader.Close()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if runtime.NumGoroutine() > before {
+		t.Error("goroutines left running after the consumer stopped reading")
+	}
+}
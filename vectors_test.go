@@ -0,0 +1,183 @@
+package decouplet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"testing"
+)
+
+var updateVectors = flag.Bool("update", false, "rewrite testdata/vectors.json")
+
+// testVector is a published encoding which must be reproduced exactly
+// from the same input, key and seed.
+type testVector struct {
+	Name    string `json:"name"`
+	KeyType string `json:"key_type"`
+	Key     string `json:"key"`
+	Seed    int64  `json:"seed"`
+	Format  string `json:"format"`
+	Options string `json:"options,omitempty"`
+	Input   string `json:"input"`
+	Output  string `json:"output"`
+}
+
+// vectorBytesKey returns the bytes key used by vectors, 256 bytes of (i*37+11) mod 256.
+func vectorBytesKey() []byte {
+	key := make([]byte, 256)
+	for i := range key {
+		key[i] = byte(i*37 + 11)
+	}
+	return key
+}
+
+// vectorImageKey returns the image key used by vectors, a 320x320 image
+// where each pixel is {x*7+y*3, x*y, x^y, 255} in NRGBA.
+func vectorImageKey() image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 320, 320))
+	for x := 0; x < 320; x++ {
+		for y := 0; y < 320; y++ {
+			img.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(x*7 + y*3),
+				G: uint8(x * y),
+				B: uint8(x ^ y),
+				A: 255,
+			})
+		}
+	}
+	return img
+}
+
+func (v testVector) key(t *testing.T) Key {
+	switch v.KeyType {
+	case "bytes":
+		key, err := hex.DecodeString(v.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewBytesKey(key)
+	case "text":
+		key, err := NewTextKey(v.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	case "image":
+		return NewImageKey(vectorImageKey())
+	}
+	t.Fatal("unknown key type:", v.KeyType)
+	return nil
+}
+
+func (v testVector) options() []Option {
+	opts := []Option{WithSeed(v.Seed)}
+	if v.Format == "binary" {
+		opts = append(opts, WithFormat(FormatBinary))
+	}
+	switch v.Options {
+	case "fingerprint":
+		opts = append(opts, WithFingerprint())
+	case "integrity":
+		opts = append(opts, WithIntegrity())
+	}
+	return opts
+}
+
+func TestVectors(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []testVector
+	err = json.Unmarshal(data, &vectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range vectors {
+		output, err := Encode([]byte(v.Input), v.key(t), v.options()...)
+		if err != nil {
+			t.Error(v.Name, err)
+			continue
+		}
+		if *updateVectors {
+			vectors[i].Output = hex.EncodeToString(output)
+			continue
+		}
+		if hex.EncodeToString(output) != v.Output {
+			t.Error(v.Name, "output does not match vector:", string(output))
+		}
+		again, err := Encode([]byte(v.Input), v.key(t), v.options()...)
+		if err != nil || hex.EncodeToString(again) != hex.EncodeToString(output) {
+			t.Error(v.Name, "output is not reproducible")
+		}
+		decoded, err := Decode(output, v.key(t))
+		if err != nil {
+			t.Error(v.Name, err)
+		}
+		if string(decoded) != v.Input {
+			t.Error(v.Name, "vector does not decode to its input")
+		}
+	}
+	if *updateVectors {
+		data, err = json.MarshalIndent(vectors, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile("testdata/vectors.json", append(data, '\n'), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSeedStream(t *testing.T) {
+	key := NewBytesKey(vectorBytesKey())
+	msg := []byte("Test this message twice with the same seed")
+	outputs := make([][]byte, 2)
+	for i := range outputs {
+		reader, err := EncodeStream(bytes.NewReader(msg), key,
+			WithSeed(9), WithFormat(FormatBinary), WithFingerprint())
+		if err != nil {
+			t.Fatal(err)
+		}
+		outputs[i], err = ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(outputs[0], outputs[1]) {
+		t.Error("streams with the same seed are not equal")
+	}
+	other, err := Encode(msg, key,
+		WithSeed(10), WithFormat(FormatBinary), WithFingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(outputs[0], other) {
+		t.Error("streams with different seeds are equal")
+	}
+}
+
+func TestRandomReader(t *testing.T) {
+	key := NewBytesKey(vectorBytesKey())
+	source := bytes.Repeat([]byte("decouplet"), 512)
+	first, err := Encode([]byte("Test"), key, WithRandom(bytes.NewReader(source)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Encode([]byte("Test"), key, WithRandom(bytes.NewReader(source)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Error("output from the same random reader is not equal")
+	}
+	_, err = Encode([]byte("Test"), key, WithRandom(bytes.NewReader(nil)))
+	if err == nil {
+		t.Error("expected error from an empty random reader")
+	}
+}